@@ -24,15 +24,30 @@ const (
 	ServicePanic   = 4
 )
 
+// 框架保留错误码 [900, 999], 新增的内置错误码在此范围内分配, 业务错误码不要使用
+const (
+	reservedMin = 900
+	reservedMax = 999
+
+	Conflict = 909 // 并发修改冲突(乐观锁)
+)
+
 var mutex sync.Mutex
 
 var prefix = "miskit"
 
+const undefined = "未定义错误码"
+
 var msg = map[int]string{
 	Success:        "ok",
 	InvalidRequest: "请求无效",
 	UnknownError:   "未知错误",
 	ServicePanic:   "程序崩溃",
+	Conflict:       "数据已被修改",
+}
+
+func init() {
+	limitMetric()
 }
 
 func (e Error) String() string {
@@ -76,6 +91,7 @@ func (e Error) Record(ctx context.Context) Error {
 	return e
 }
 
+// Register 注册业务错误码, 不能使用内置错误码及框架保留错误码[900, 999]
 func Register(name string, errno map[int]string) {
 	mutex.Lock()
 	defer mutex.Unlock()
@@ -84,10 +100,28 @@ func Register(name string, errno map[int]string) {
 
 	for errno, message := range errno {
 		if _, exists := msg[errno]; exists {
+			if errno >= reservedMin && errno <= reservedMax {
+				panic(fmt.Sprintf("errno: %d is reserved by framework [%d, %d]", errno, reservedMin, reservedMax))
+			}
 			panic(fmt.Sprintf("errno: Define called twice for errno %d", errno))
 		}
 		msg[errno] = message
 	}
+
+	limitMetric()
+}
+
+// 错误监控的message标签只保留已注册的错误信息, NewMessage透传的信息归入__other__
+func limitMetric() {
+	var messages = make([]string, 0, len(msg)+1)
+	for _, message := range msg {
+		messages = append(messages, message)
+	}
+	messages = append(messages, undefined)
+
+	metric.Default.SetLimit("errors::"+prefix+"_total", metric.Limit{
+		Allow: map[string][]string{"message": messages},
+	})
 }
 
 func New(errno int, error error) Error {
@@ -104,11 +138,30 @@ func New(errno int, error error) Error {
 	}
 }
 
+// NewMessage 创建错误并指定错误信息, 用于透传下游服务的错误码和错误信息
+func NewMessage(errno int, message string, error error) Error {
+	if err, ok := error.(Error); ok {
+		return err
+	}
+
+	return Error{
+		errno:   errno,
+		error:   error,
+		message: message,
+		record:  false,
+		metric:  false,
+	}
+}
+
+func (e Error) Message() string {
+	return e.message
+}
+
 func Msg(errno int) string {
 	if msg, exists := msg[errno]; exists {
 		return msg
 	}
-	return "未定义错误码"
+	return undefined
 }
 
 func Is(err error, errno int) bool {
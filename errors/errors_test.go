@@ -3,6 +3,10 @@ package errors
 import (
 	"encoding/json"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zooyer/miskit/metric"
 )
 
 func TestTTT(t *testing.T) {
@@ -21,3 +25,23 @@ func TestTTT(t *testing.T) {
 
 	t.Logf("%#v", b)
 }
+
+func TestError_Metric(t *testing.T) {
+	New(InvalidRequest, nil).Metric()
+	NewMessage(InvalidRequest, "user 10086 not found", nil).Metric()
+
+	var messages = make(map[string]float64)
+	for _, family := range metric.Default.Gather() {
+		if family.Name != "errors::"+prefix+"_total" {
+			continue
+		}
+		for _, sample := range family.Samples {
+			messages[sample.Values[1]] += sample.Value
+		}
+	}
+
+	assert.Equal(t, map[string]float64{
+		Msg(InvalidRequest): 1,
+		metric.Other:        1,
+	}, messages)
+}
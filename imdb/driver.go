@@ -44,5 +44,9 @@ func Open(dialect string, args string) (conn Conn, err error) {
 		return nil, fmt.Errorf("imdb: unknown driver %q (forgotten import?)", dialect)
 	}
 
-	return driver.Open(args)
+	if conn, err = driver.Open(args); err != nil {
+		return
+	}
+
+	return newTraceConn(dialect, conn), nil
 }
@@ -0,0 +1,75 @@
+package imdb
+
+import (
+	"context"
+
+	"github.com/zooyer/miskit/trace"
+)
+
+// 记录调用span的连接
+type traceConn struct {
+	conn    Conn
+	dialect string
+}
+
+func newTraceConn(dialect string, conn Conn) Conn {
+	return &traceConn{
+		conn:    conn,
+		dialect: dialect,
+	}
+}
+
+// ctx中存在trace时创建客户端span
+func (c *traceConn) start(ctx context.Context, command, key string) (context.Context, *trace.Span) {
+	if trace.Get(ctx) == nil {
+		return ctx, nil
+	}
+
+	return trace.StartSpan(ctx, "imdb "+command, trace.WithKind(trace.KindClient), trace.WithAttributes(map[string]interface{}{
+		"db.system":    c.dialect,
+		"db.operation": command,
+		"db.key":       key,
+	}))
+}
+
+func (c *traceConn) end(span *trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+func (c *traceConn) Get(ctx context.Context, key string) (value string, err error) {
+	ctx, span := c.start(ctx, "GET", key)
+	defer func() { c.end(span, err) }()
+
+	return c.conn.Get(ctx, key)
+}
+
+func (c *traceConn) Set(ctx context.Context, key, value string) (err error) {
+	ctx, span := c.start(ctx, "SET", key)
+	defer func() { c.end(span, err) }()
+
+	return c.conn.Set(ctx, key, value)
+}
+
+func (c *traceConn) SetEx(ctx context.Context, key, value string, seconds int64) (err error) {
+	ctx, span := c.start(ctx, "SETEX", key)
+	defer func() { c.end(span, err) }()
+
+	return c.conn.SetEx(ctx, key, value, seconds)
+}
+
+func (c *traceConn) Del(ctx context.Context, key string) (err error) {
+	ctx, span := c.start(ctx, "DEL", key)
+	defer func() { c.end(span, err) }()
+
+	return c.conn.Del(ctx, key)
+}
+
+func (c *traceConn) TTL(ctx context.Context, key string) (seconds int64, err error) {
+	ctx, span := c.start(ctx, "TTL", key)
+	defer func() { c.end(span, err) }()
+
+	return c.conn.TTL(ctx, key)
+}
@@ -17,6 +17,7 @@ type Config struct {
 	Level    string
 	Align    bool
 	Interval time.Duration
+	Baggage  []string // 作为日志标签输出的trace baggage key
 }
 
 type Logger struct {
@@ -141,6 +142,13 @@ func (l *Logger) trace(ctx context.Context, record *Record) {
 		if len(t.Content) != 0 {
 			record.Tag = append(record.Tag, Tag{Key: "content", Value: t.Content})
 		}
+		if l.config != nil {
+			for _, key := range l.config.Baggage {
+				if value, exists := t.Baggage[key]; exists {
+					record.Tag = append(record.Tag, Tag{Key: key, Value: value})
+				}
+			}
+		}
 	}
 }
 
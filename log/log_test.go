@@ -3,6 +3,7 @@ package log
 import (
 	"context"
 	"encoding/json"
+	"github.com/stretchr/testify/assert"
 	"github.com/zooyer/miskit/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -88,3 +89,38 @@ func TestNew(t *testing.T) {
 
 	log.Debug(ctx, "Hello")
 }
+
+type tagRecorder struct {
+	tags []Tag
+}
+
+func (r *tagRecorder) Record(record ...*Record) {
+	for _, record := range record {
+		r.tags = append(r.tags, record.Tag...)
+	}
+}
+
+func (r *tagRecorder) Close() {}
+
+func TestLogger_Baggage(t *testing.T) {
+	var recorder = new(tagRecorder)
+	log, err := New(Config{Level: "DEBUG", Baggage: []string{"user_id", "tenant"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.SetDefaultRecorder(recorder)
+
+	var ctx = trace.Set(context.Background(), trace.New(nil, "test"))
+	ctx, _ = trace.SetBaggage(ctx, "user_id", "10001")
+	ctx, _ = trace.SetBaggage(ctx, "secret", "value")
+
+	log.Info(ctx, "baggage")
+
+	var tags = make(map[string]interface{})
+	for _, tag := range recorder.tags {
+		tags[tag.Key] = tag.Value
+	}
+	assert.Equal(t, "10001", tags["user_id"])
+	assert.NotContains(t, tags, "tenant")
+	assert.NotContains(t, tags, "secret")
+}
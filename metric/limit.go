@@ -0,0 +1,174 @@
+package metric
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Other 超出基数限制或不在白名单中的标签值
+const Other = "__other__"
+
+const overflowName = "metric_cardinality_overflow_total"
+
+// DefaultMaxSeries 未设置Limit.MaxSeries时每个指标的最大标签组合数
+var DefaultMaxSeries = 10000
+
+// Limit 标签基数限制
+type Limit struct {
+	// MaxSeries 最大标签组合数, 超出后新的组合全部归入__other__; 0为DefaultMaxSeries, 负数不限制
+	MaxSeries int
+	// Allow 标签值白名单, key为标签名, 不在白名单中的值替换为__other__
+	Allow map[string][]string
+}
+
+type limit struct {
+	maxSeries int
+	allow     []map[string]struct{} // 按标签顺序, nil表示该标签不限制
+}
+
+// SetLimit 设置指标的基数限制, 指标未注册时在注册时生效, 已有的标签组合不受影响
+func (r *Registry) SetLimit(name string, limit Limit) {
+	name = sanitize(name)
+
+	r.mutex.Lock()
+	r.limits[name] = limit
+	f := r.families[name]
+	r.mutex.Unlock()
+
+	if f != nil {
+		f.setLimit(limit)
+	}
+}
+
+// 超限告警计数器, 首次超限时注册, 自身不限制基数(标签组合不超过指标数量的两倍)
+func (r *Registry) warn(name string) func(reason string) {
+	if name == overflowName {
+		return nil
+	}
+
+	return func(reason string) {
+		r.once.Do(func() {
+			r.overflow = r.Counter(Opts{
+				Name:   overflowName,
+				Help:   "Total number of label values collapsed into __other__ by cardinality limits.",
+				Labels: []string{"metric", "reason"},
+				Limit:  Limit{MaxSeries: -1},
+			})
+		})
+		r.overflow.WithLabelValues(name, reason).Inc()
+	}
+}
+
+func (f *family) setLimit(opts Limit) {
+	var l = &limit{maxSeries: opts.MaxSeries}
+	if l.maxSeries == 0 {
+		l.maxSeries = DefaultMaxSeries
+	}
+
+	for i, label := range f.opts.Labels {
+		values, exists := opts.Allow[label]
+		if !exists {
+			continue
+		}
+		if l.allow == nil {
+			l.allow = make([]map[string]struct{}, len(f.opts.Labels))
+		}
+		l.allow[i] = make(map[string]struct{}, len(values))
+		for _, value := range values {
+			l.allow[i][value] = struct{}{}
+		}
+	}
+
+	f.limit.Store(l)
+}
+
+// 白名单过滤, 有替换时复制values, 不修改调用方的切片
+func (f *family) filter(l *limit, values []string) []string {
+	var copied bool
+	for i, allow := range l.allow {
+		if allow == nil {
+			continue
+		}
+		if _, exists := allow[values[i]]; exists {
+			continue
+		}
+		if !copied {
+			values = append([]string(nil), values...)
+			copied = true
+		}
+		values[i] = Other
+		if f.warn != nil {
+			f.warn("allow")
+		}
+	}
+	return values
+}
+
+// 超出基数限制, 所有标签值归入__other__, 调用方持有写锁
+func (f *family) overflow() interface{} {
+	if f.warn != nil {
+		f.warn("limit")
+	}
+
+	var values = make([]string, len(f.opts.Labels))
+	for i := range values {
+		values[i] = Other
+	}
+
+	var hash = hashValues(values)
+	for _, c := range f.children[hash] {
+		if equalStrings(c.values, values) {
+			return c.metric
+		}
+	}
+
+	return f.add(hash, values)
+}
+
+// NormalizePath 归一化URL路径用作标签: 去掉查询参数, 数字、UUID和长十六进制段替换为:id
+//
+//	/user/123/order/9f8e7d6c-1a2b-4c3d-8e9f-0a1b2c3d4e5f?page=1 => /user/:id/order/:id
+func NormalizePath(path string) string {
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	if u, err := url.PathUnescape(path); err == nil {
+		path = u
+	}
+
+	var segments = strings.Split(path, "/")
+	for i, segment := range segments {
+		if isID(segment) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func isID(segment string) bool {
+	if segment == "" {
+		return false
+	}
+
+	var digits = true
+	for i := 0; i < len(segment); i++ {
+		switch c := segment[i]; {
+		case c >= '0' && c <= '9':
+		case c >= 'a' && c <= 'f', c >= 'A' && c <= 'F':
+			digits = false
+		case c == '-' && len(segment) == 36:
+			digits = false
+		default:
+			return false
+		}
+	}
+
+	if digits {
+		return true
+	}
+	if len(segment) == 36 {
+		return segment[8] == '-' && segment[13] == '-' && segment[18] == '-' && segment[23] == '-' &&
+			strings.Count(segment, "-") == 4
+	}
+	return len(segment) >= 16 && !strings.Contains(segment, "-")
+}
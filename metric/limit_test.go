@@ -0,0 +1,149 @@
+package metric
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func gatherSamples(registry *Registry, name string) []Sample {
+	for _, family := range registry.Gather() {
+		if family.Name == name {
+			return family.Samples
+		}
+	}
+	return nil
+}
+
+func overflowValue(registry *Registry, name, reason string) float64 {
+	for _, sample := range gatherSamples(registry, overflowName) {
+		if sample.Values[0] == name && sample.Values[1] == reason {
+			return sample.Value
+		}
+	}
+	return 0
+}
+
+func TestLimit_MaxSeries(t *testing.T) {
+	var (
+		registry = NewRegistry()
+		counter  = registry.Counter(Opts{
+			Name:   "requests_total",
+			Labels: []string{"path", "code"},
+			Limit:  Limit{MaxSeries: 2},
+		})
+	)
+
+	for i := 0; i < 5; i++ {
+		counter.WithLabelValues("/"+strconv.Itoa(i), "200").Inc()
+	}
+	counter.WithLabelValues("/0", "200").Inc()
+
+	assert.Equal(t, []Sample{
+		{Values: []string{"/0", "200"}, Value: 2},
+		{Values: []string{"/1", "200"}, Value: 1},
+		{Values: []string{Other, Other}, Value: 3},
+	}, gatherSamples(registry, "requests_total"))
+	assert.Equal(t, float64(3), overflowValue(registry, "requests_total", "limit"))
+}
+
+func TestLimit_Allow(t *testing.T) {
+	var (
+		registry = NewRegistry()
+		counter  = registry.Counter(Opts{
+			Name:   "errors_total",
+			Labels: []string{"errno", "message"},
+			Limit:  Limit{Allow: map[string][]string{"message": {"ok"}}},
+		})
+		values = []string{"1", "user 123 not found"}
+	)
+
+	counter.WithLabelValues("0", "ok").Inc()
+	counter.WithLabelValues(values...).Inc()
+	assert.Equal(t, "user 123 not found", values[1])
+
+	// SetLimit对已注册的指标生效
+	registry.SetLimit("errors_total", Limit{Allow: map[string][]string{"message": {"ok", "not found"}}})
+	counter.WithLabelValues("2", "not found").Inc()
+
+	assert.Equal(t, []Sample{
+		{Values: []string{"0", "ok"}, Value: 1},
+		{Values: []string{"1", Other}, Value: 1},
+		{Values: []string{"2", "not found"}, Value: 1},
+	}, gatherSamples(registry, "errors_total"))
+	assert.Equal(t, float64(1), overflowValue(registry, "errors_total", "allow"))
+}
+
+func TestRegistry_SetLimit(t *testing.T) {
+	var registry = NewRegistry()
+	registry.SetLimit("jobs::total", Limit{MaxSeries: 1})
+
+	var gauge = registry.Gauge(Opts{Name: "jobs::total", Labels: []string{"job"}})
+	gauge.WithLabelValues("a").Set(1)
+	gauge.WithLabelValues("b").Set(2)
+
+	assert.Equal(t, []Sample{
+		{Values: []string{Other}, Value: 2},
+		{Values: []string{"a"}, Value: 1},
+	}, gatherSamples(registry, "jobs::total"))
+
+	var unlimited = registry.Counter(Opts{Name: "unlimited_total", Labels: []string{"id"}, Limit: Limit{MaxSeries: -1}})
+	for i := 0; i < 100; i++ {
+		unlimited.WithLabelValues(strconv.Itoa(i)).Inc()
+	}
+	assert.Len(t, gatherSamples(registry, "unlimited_total"), 100)
+	assert.Equal(t, float64(0), overflowValue(registry, "unlimited_total", "limit"))
+}
+
+func TestNormalizePath(t *testing.T) {
+	var tests = map[string]string{
+		"":                           "",
+		"/":                          "/",
+		"/user/list":                 "/user/list",
+		"/user/123":                  "/user/:id",
+		"/user/123/order/456?page=1": "/user/:id/order/:id",
+		"/v1/user/abc#top":           "/v1/user/abc",
+		"/file/9f8e7d6c-1a2b-4c3d-8e9f-0a1b2c3d4e5f": "/file/:id",
+		"/commit/0123456789abcdef0123":               "/commit/:id",
+		"/hash/deadbeef":                             "/hash/deadbeef",
+		"/api/v2/add-user":                           "/api/v2/add-user",
+		"/user/%31%32":                               "/user/:id",
+	}
+
+	for path, expect := range tests {
+		assert.Equal(t, expect, NormalizePath(path), path)
+	}
+}
+
+func TestRpc_Normalize(t *testing.T) {
+	Rpc("test_normalize", "/api/user/1", "/api/order/2?x=1", 200, 0, nil)
+	Rpc("test_normalize", "/api/user/3", "/api/order/4", 200, 0, nil)
+
+	for _, family := range Default.Gather() {
+		if family.Name != "rpc_requests_total" {
+			continue
+		}
+		for _, sample := range family.Samples {
+			if sample.Values[0] == "test_normalize" {
+				assert.Equal(t, []string{"test_normalize", "/api/user/:id", "/api/order/:id", "200"}, sample.Values)
+				assert.Equal(t, float64(2), sample.Value)
+				return
+			}
+		}
+	}
+	t.Fatal("rpc sample not found")
+}
+
+func BenchmarkFamily_Allow(b *testing.B) {
+	var counter = NewRegistry().Counter(Opts{
+		Name:   "bench_total",
+		Labels: []string{"code"},
+		Limit:  Limit{Allow: map[string][]string{"code": {"200"}}},
+	})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		counter.WithLabelValues("200").Inc()
+	}
+}
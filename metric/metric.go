@@ -2,21 +2,85 @@ package metric
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 )
 
 var debug bool
 
+type countVec struct {
+	keys []string // tag原始key, 对应标签顺序
+	vec  *CounterVec
+}
+
+var (
+	rpcLabels = []string{"name", "caller", "callee", "code"}
+	rpcLimit  = Limit{MaxSeries: 2000}
+
+	rpcRequests = Default.Counter(Opts{
+		Name:   "rpc_requests_total",
+		Help:   "Total number of rpc requests.",
+		Labels: rpcLabels,
+		Limit:  rpcLimit,
+	})
+	rpcLatency = Default.Histogram(Opts{
+		Name:   "rpc_request_duration_seconds",
+		Help:   "Rpc request latency in seconds.",
+		Labels: rpcLabels,
+		Limit:  rpcLimit,
+	})
+
+	// Count按名称缓存计数器及标签
+	counts sync.Map
+)
+
+// Rpc 记录一次调用的请求数和耗时, caller和callee按NormalizePath归一化, tag仅用于调试输出
 func Rpc(name, caller, callee string, code int, latency time.Duration, tag map[string]interface{}) {
 	if debug {
 		fmt.Println("[METRIC - RPC]", time.Now(), name, caller, callee, code, latency, tag)
 	}
+
+	var values = []string{name, NormalizePath(caller), NormalizePath(callee), strconv.Itoa(code)}
+
+	rpcRequests.WithLabelValues(values...).Inc()
+	rpcLatency.WithLabelValues(values...).Observe(latency.Seconds())
 }
 
+// Count 计数, 以name_total为指标名, 首次调用时tag的key作为标签, 可通过Default.SetLimit限制标签基数
 func Count(name string, count int, tag map[string]interface{}) {
 	if debug {
 		fmt.Println("[METRIC - COUNT]", time.Now(), name, count, tag)
 	}
+
+	value, ok := counts.Load(name)
+	if !ok {
+		var keys = make([]string, 0, len(tag))
+		for key := range tag {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		// 与已注册的同名指标冲突时丢弃, 不影响业务
+		f, err := Default.register(Opts{Name: name + "_total", Labels: keys}, TypeCounter)
+		if err != nil {
+			return
+		}
+		value, _ = counts.LoadOrStore(name, &countVec{keys: keys, vec: &CounterVec{family: f}})
+	}
+
+	var (
+		c      = value.(*countVec)
+		values = make([]string, len(c.keys))
+	)
+	for i, key := range c.keys {
+		if value, exists := tag[key]; exists {
+			values[i] = fmt.Sprint(value)
+		}
+	}
+
+	c.vec.WithLabelValues(values...).Add(float64(count))
 }
 
 func SetDebug(dbg bool) {
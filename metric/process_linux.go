@@ -0,0 +1,102 @@
+package metric
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	userHZ   = 100 // /proc/[pid]/stat中cpu时间的单位(sysconf(_SC_CLK_TCK), linux固定为100)
+	procRoot = "/proc"
+)
+
+// 读取/proc/[pid], pid为0时读取当前进程
+func readProcess(pid int) (*processStat, error) {
+	var dir = procRoot + "/self"
+	if pid > 0 {
+		dir = procRoot + "/" + strconv.Itoa(pid)
+	}
+
+	data, err := ioutil.ReadFile(dir + "/stat")
+	if err != nil {
+		return nil, err
+	}
+
+	// comm字段可能包含空格, 从最后一个')'之后解析
+	var index = bytes.LastIndexByte(data, ')')
+	if index < 0 {
+		return nil, errors.New("metric: invalid /proc stat")
+	}
+
+	// 从state(第3个字段)开始
+	var fields = strings.Fields(string(data[index+1:]))
+	if len(fields) < 22 {
+		return nil, errors.New("metric: invalid /proc stat")
+	}
+
+	var (
+		stat  processStat
+		parse = func(i int) float64 {
+			value, _ := strconv.ParseFloat(fields[i-3], 64)
+			return value
+		}
+	)
+
+	stat.cpu = (parse(14) + parse(15)) / userHZ
+	stat.vsize = int64(parse(23))
+	stat.rss = int64(parse(24)) * int64(os.Getpagesize())
+
+	if boot, err := bootTime(); err == nil {
+		stat.start = boot + parse(22)/userHZ
+	}
+
+	if fds, err := ioutil.ReadDir(dir + "/fd"); err == nil {
+		stat.openFDs = len(fds)
+	}
+
+	stat.maxFDs = maxFDs(dir + "/limits")
+
+	return &stat, nil
+}
+
+// 系统启动时间(unix秒)
+func bootTime() (float64, error) {
+	file, err := os.Open(procRoot + "/stat")
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var scanner = bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "btime ") {
+			return strconv.ParseFloat(strings.TrimSpace(line[6:]), 64)
+		}
+	}
+
+	return 0, errors.New("metric: btime not found")
+}
+
+// 读取limits中的Max open files软限制
+func maxFDs(filename string) int {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return 0
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "Max open files") {
+			if fields := strings.Fields(line[len("Max open files"):]); len(fields) > 0 {
+				value, _ := strconv.Atoi(fields[0])
+				return value
+			}
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package metric
+
+import "errors"
+
+func readProcess(pid int) (*processStat, error) {
+	return nil, errors.New("metric: process metrics are only supported on linux")
+}
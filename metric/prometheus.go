@@ -0,0 +1,157 @@
+package metric
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 内容类型
+const (
+	ContentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+var (
+	helpEscaper            = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	openMetricsHelpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	labelEscaper           = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// Handler 暴露指标的HTTP处理器, 请求头Accept包含application/openmetrics-text时输出OpenMetrics,
+// 否则输出Prometheus文本格式; registry为nil时使用Default
+func Handler(registry *Registry) http.Handler {
+	if registry == nil {
+		registry = Default
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			families = registry.Gather()
+			write    = WriteText
+		)
+
+		if strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text") {
+			w.Header().Set("Content-Type", ContentTypeOpenMetrics)
+			write = WriteOpenMetrics
+		} else {
+			w.Header().Set("Content-Type", ContentTypeText)
+		}
+
+		_ = write(w, families)
+	})
+}
+
+// WriteText 以Prometheus文本格式(0.0.4)输出
+func WriteText(w io.Writer, families []Family) error {
+	var buf = bufio.NewWriter(w)
+
+	for _, family := range families {
+		if len(family.Samples) == 0 {
+			continue
+		}
+
+		if family.Help != "" {
+			buf.WriteString("# HELP " + family.Name + " " + helpEscaper.Replace(family.Help) + "\n")
+		}
+		buf.WriteString("# TYPE " + family.Name + " " + family.Type.String() + "\n")
+
+		writeSamples(buf, family, family.Name, formatFloat)
+	}
+
+	return buf.Flush()
+}
+
+// WriteOpenMetrics 以OpenMetrics 1.0格式输出, 计数器的指标族名去掉_total后缀, 采样名带_total后缀
+func WriteOpenMetrics(w io.Writer, families []Family) error {
+	var buf = bufio.NewWriter(w)
+
+	for _, family := range families {
+		if len(family.Samples) == 0 {
+			continue
+		}
+
+		var (
+			name   = family.Name
+			sample = family.Name
+		)
+		if family.Type == TypeCounter {
+			name = strings.TrimSuffix(name, "_total")
+			sample = name + "_total"
+		}
+
+		buf.WriteString("# TYPE " + name + " " + family.Type.String() + "\n")
+		if family.Help != "" {
+			buf.WriteString("# HELP " + name + " " + openMetricsHelpEscaper.Replace(family.Help) + "\n")
+		}
+
+		writeSamples(buf, family, sample, formatOpenMetricsFloat)
+	}
+
+	buf.WriteString("# EOF\n")
+
+	return buf.Flush()
+}
+
+func writeSamples(buf *bufio.Writer, family Family, name string, format func(float64) string) {
+	for _, sample := range family.Samples {
+		if family.Type != TypeHistogram {
+			writeSample(buf, name, family.Labels, sample.Values, "", "", formatFloat(sample.Value))
+			continue
+		}
+
+		for _, bucket := range sample.Buckets {
+			writeSample(buf, name+"_bucket", family.Labels, sample.Values, "le", format(bucket.Upper), strconv.FormatUint(bucket.Count, 10))
+		}
+		writeSample(buf, name+"_bucket", family.Labels, sample.Values, "le", "+Inf", strconv.FormatUint(sample.Count, 10))
+		writeSample(buf, name+"_sum", family.Labels, sample.Values, "", "", formatFloat(sample.Sum))
+		writeSample(buf, name+"_count", family.Labels, sample.Values, "", "", strconv.FormatUint(sample.Count, 10))
+	}
+}
+
+func writeSample(buf *bufio.Writer, name string, labels, values []string, extraLabel, extraValue, value string) {
+	buf.WriteString(name)
+
+	if len(labels) > 0 || extraLabel != "" {
+		buf.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(label + `="` + labelEscaper.Replace(values[i]) + `"`)
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(extraLabel + `="` + extraValue + `"`)
+		}
+		buf.WriteByte('}')
+	}
+
+	buf.WriteString(" " + value + "\n")
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// OpenMetrics的le使用规范浮点数, 整数带.0
+func formatOpenMetricsFloat(value float64) string {
+	var s = formatFloat(value)
+	if strings.ContainsAny(s, ".eEIN") {
+		return s
+	}
+	return s + ".0"
+}
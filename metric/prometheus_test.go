@@ -0,0 +1,87 @@
+package metric
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func goldenRegistry() *Registry {
+	var registry = NewRegistry()
+
+	var requests = registry.Counter(Opts{Name: "http_requests_total", Help: "Total requests.\nWith \\ newline.", Labels: []string{"method", "path"}})
+	requests.WithLabelValues("GET", "/user").Add(3)
+	requests.WithLabelValues("POST", `/say "hi"\n`).Inc()
+
+	registry.Counter(Opts{Name: "events", Help: "Counter without suffix."}).WithLabelValues().Add(1.5)
+	registry.Gauge(Opts{Name: "temperature", Labels: []string{"room"}}).WithLabelValues("a").Set(-2.5)
+	registry.Gauge(Opts{Name: "infinity"}).WithLabelValues().Set(math.Inf(1))
+	registry.Gauge(Opts{Name: "empty", Help: "Family without samples."})
+
+	var latency = registry.Histogram(Opts{Name: "latency_seconds", Help: "Latency.", Labels: []string{"name"}, Buckets: []float64{0.1, 1, 10}})
+	for _, value := range []float64{0.05, 0.5, 5, 50} {
+		latency.WithLabelValues("rpc").Observe(value)
+	}
+	registry.Histogram(Opts{Name: "size_bytes", Buckets: []float64{100}}).WithLabelValues().Observe(10)
+
+	return registry
+}
+
+func golden(t *testing.T, name string, data []byte) {
+	var filename = filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(expected), string(data))
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteText(&buf, goldenRegistry().Gather()))
+	golden(t, "metrics.prom", buf.Bytes())
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteOpenMetrics(&buf, goldenRegistry().Gather()))
+	golden(t, "metrics.openmetrics", buf.Bytes())
+}
+
+func TestHandler(t *testing.T) {
+	var handler = Handler(goldenRegistry())
+
+	var res = httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, ContentTypeText, res.Header().Get("Content-Type"))
+	assert.Contains(t, res.Body.String(), `http_requests_total{method="GET",path="/user"} 3`)
+
+	res = httptest.NewRecorder()
+	var req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0,text/plain;q=0.5")
+	handler.ServeHTTP(res, req)
+	assert.Equal(t, ContentTypeOpenMetrics, res.Header().Get("Content-Type"))
+	assert.True(t, strings.HasSuffix(res.Body.String(), "# EOF\n"))
+
+	// 默认注册表包含运行时和进程指标
+	res = httptest.NewRecorder()
+	Handler(nil).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, res.Body.String(), "go_goroutines ")
+	assert.Contains(t, res.Body.String(), "go_memstats_heap_alloc_bytes ")
+}
@@ -0,0 +1,132 @@
+package metric
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PushOption Pushgateway上报选项
+type PushOption struct {
+	URL              string            // Pushgateway地址, 如http://127.0.0.1:9091
+	Job              string            // job名称, 必填
+	Grouping         map[string]string // 分组标签
+	Method           string            // PUT替换分组内全部指标(默认), POST只替换同名指标
+	Headers          map[string]string // 附加请求头(鉴权等)
+	Client           *http.Client      // 自定义客户端, 默认超时10s
+	DeleteOnShutdown bool              // 关闭时删除分组(批处理任务结束后不再保留指标)
+}
+
+// Pusher Pushgateway上报器, 以Prometheus文本格式推送
+type Pusher struct {
+	option PushOption
+	client *http.Client
+	url    string
+}
+
+// NewPusher 创建Pushgateway上报器
+func NewPusher(option PushOption) (*Pusher, error) {
+	if option.Job == "" {
+		return nil, fmt.Errorf("metric: pushgateway job is required")
+	}
+	if option.Method == "" {
+		option.Method = http.MethodPut
+	}
+
+	var client = option.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Pusher{
+		option: option,
+		client: client,
+		url:    strings.TrimSuffix(option.URL, "/") + "/metrics" + groupingPath(option.Job, option.Grouping),
+	}, nil
+}
+
+// /job/<job>/<label>/<value>..., 值包含/时使用base64编码, 空值为@base64/=
+func groupingPath(job string, grouping map[string]string) string {
+	var keys = make([]string, 0, len(grouping))
+	for key := range grouping {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var path = "/" + pathSegment("job", job)
+	for _, key := range keys {
+		path += "/" + pathSegment(sanitize(key), grouping[key])
+	}
+
+	return path
+}
+
+func pathSegment(label, value string) string {
+	if value == "" {
+		return label + "@base64/="
+	}
+	if strings.Contains(value, "/") {
+		return label + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return label + "/" + url.PathEscape(value)
+}
+
+func (p *Pusher) Report(ctx context.Context, families []Family) error {
+	var buf bytes.Buffer
+	if err := WriteText(&buf, families); err != nil {
+		return err
+	}
+
+	return p.do(ctx, p.option.Method, &buf)
+}
+
+// Delete 删除分组内的指标
+func (p *Pusher) Delete(ctx context.Context) error {
+	return p.do(ctx, http.MethodDelete, nil)
+}
+
+func (p *Pusher) Close() error {
+	if !p.option.DeleteOnShutdown {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return p.Delete(ctx)
+}
+
+func (p *Pusher) do(ctx context.Context, method string, body io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, method, p.url, body)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", ContentTypeText)
+	}
+	for key, value := range p.option.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("metric: push to %s failed, status code %d: %s", p.url, resp.StatusCode, data)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package metric
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pushRequest struct {
+	method string
+	path   string
+	body   string
+}
+
+func pushgateway(t *testing.T, code int) (*httptest.Server, func() []pushRequest) {
+	var (
+		mutex    sync.Mutex
+		requests []pushRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		requests = append(requests, pushRequest{method: r.Method, path: r.URL.EscapedPath(), body: string(data)})
+		mutex.Unlock()
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []pushRequest {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]pushRequest(nil), requests...)
+	}
+}
+
+func TestPusher(t *testing.T) {
+	server, requests := pushgateway(t, http.StatusOK)
+
+	var registry = NewRegistry()
+	registry.Counter(Opts{Name: "jobs_total"}).WithLabelValues().Inc()
+
+	pusher, err := NewPusher(PushOption{
+		URL:              server.URL,
+		Job:              "batch",
+		Grouping:         map[string]string{"instance": "host-1", "path": "/data/x", "empty": ""},
+		DeleteOnShutdown: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var scheduler = NewScheduler(registry, pusher, ReportOption{Interval: time.Hour})
+	assert.NoError(t, scheduler.Shutdown(context.Background()))
+
+	var path = "/metrics/job/batch/empty@base64/=/instance/host-1/path@base64/L2RhdGEveA"
+	assert.Equal(t, []pushRequest{
+		{method: http.MethodPut, path: path, body: "# TYPE jobs_total counter\njobs_total 1\n"},
+		{method: http.MethodDelete, path: path},
+	}, requests())
+
+	_, err = NewPusher(PushOption{URL: server.URL})
+	assert.Error(t, err)
+}
+
+func TestPusher_Error(t *testing.T) {
+	server, _ := pushgateway(t, http.StatusBadRequest)
+
+	pusher, err := NewPusher(PushOption{URL: server.URL, Job: "batch", Method: http.MethodPost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, pusher.Report(context.Background(), nil))
+}
+
+func TestScheduler(t *testing.T) {
+	server, requests := pushgateway(t, http.StatusOK)
+
+	var registry = NewRegistry()
+	var counter = registry.Counter(Opts{Name: "jobs_total"})
+	counter.WithLabelValues().Inc()
+
+	pusher, err := NewPusher(PushOption{URL: server.URL, Job: "batch"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var scheduler = NewScheduler(registry, pusher, ReportOption{Interval: 10 * time.Millisecond})
+	assert.Eventually(t, func() bool { return len(requests()) >= 2 }, time.Second, time.Millisecond)
+
+	// 关闭时上报最后的值
+	counter.WithLabelValues().Add(9)
+	assert.NoError(t, scheduler.Shutdown(context.Background()))
+
+	var list = requests()
+	assert.Equal(t, "# TYPE jobs_total counter\njobs_total 10\n", list[len(list)-1].body)
+	assert.NoError(t, scheduler.Shutdown(context.Background()))
+}
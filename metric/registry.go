@@ -0,0 +1,562 @@
+package metric
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Type 指标类型
+type Type int
+
+const (
+	TypeCounter Type = iota
+	TypeGauge
+	TypeHistogram
+)
+
+// Opts 指标选项
+type Opts struct {
+	Name    string    // 指标名, 非法字符替换为下划线
+	Help    string    // 说明
+	Labels  []string  // 标签名
+	Buckets []float64 // 直方图桶上界(升序), 默认DefBuckets
+	Limit   Limit     // 标签基数限制
+}
+
+// Counter 计数器, 只增不减
+type Counter struct {
+	bits uint64
+}
+
+// Gauge 仪表盘, 可增可减
+type Gauge struct {
+	bits uint64
+}
+
+// Histogram 直方图
+type Histogram struct {
+	count  uint64 // 64位原子操作字段放在开头保证32位平台对齐
+	sum    uint64
+	upper  []float64
+	counts []uint64
+}
+
+// Bucket 直方图桶(累计数量)
+type Bucket struct {
+	Upper float64 `json:"upper"`
+	Count uint64  `json:"count"`
+}
+
+// Sample 单个标签组合的采样
+type Sample struct {
+	Values  []string `json:"values"`
+	Value   float64  `json:"value"`             // 计数器、仪表盘的值
+	Count   uint64   `json:"count,omitempty"`   // 直方图观测次数
+	Sum     float64  `json:"sum,omitempty"`     // 直方图观测总和
+	Buckets []Bucket `json:"buckets,omitempty"` // 直方图桶
+}
+
+// Family 同名指标的快照
+type Family struct {
+	Name    string   `json:"name"`
+	Help    string   `json:"help,omitempty"`
+	Type    Type     `json:"type"`
+	Labels  []string `json:"labels,omitempty"`
+	Samples []Sample `json:"samples"`
+}
+
+// Registry 指标注册表, 并发安全
+type Registry struct {
+	mutex      sync.RWMutex
+	families   map[string]*family
+	limits     map[string]Limit // SetLimit设置的限制, 优先于Opts.Limit
+	collectors []Collector
+	overflow   *CounterVec
+	once       sync.Once
+}
+
+// Collector 采集器, 每次Gather时生成指标(如运行时、进程指标)
+type Collector interface {
+	Collect() []Family
+}
+
+// CounterVec 带标签的计数器
+type CounterVec struct {
+	family *family
+}
+
+// GaugeVec 带标签的仪表盘
+type GaugeVec struct {
+	family *family
+}
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	family *family
+}
+
+type family struct {
+	opts     Opts
+	typ      Type
+	limit    atomic.Value // *limit
+	warn     func(reason string)
+	mutex    sync.RWMutex
+	children map[uint64][]*child
+	series   int // 标签组合数, 不含超限后的__other__汇总
+}
+
+type child struct {
+	values []string
+	metric interface{}
+}
+
+// DefBuckets 默认直方图桶(秒)
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default 默认注册表, 包含Go运行时和进程指标
+var Default = newDefault()
+
+// NewRegistry 创建注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		families: make(map[string]*family),
+		limits:   make(map[string]Limit),
+	}
+}
+
+func newDefault() *Registry {
+	var registry = NewRegistry()
+	registry.Register(NewGoCollector())
+	registry.Register(NewProcessCollector())
+	return registry
+}
+
+// LinearBuckets 线性桶: start, start+width, ...共count个
+func LinearBuckets(start, width float64, count int) []float64 {
+	var buckets = make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start + float64(i)*width
+	}
+	return buckets
+}
+
+// ExponentialBuckets 指数桶: start, start*factor, ...共count个
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	var buckets = make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// Counter 获取或注册计数器, 同名指标类型或标签不一致时panic
+func (r *Registry) Counter(opts Opts) *CounterVec {
+	return &CounterVec{family: r.mustRegister(opts, TypeCounter)}
+}
+
+// Gauge 获取或注册仪表盘
+func (r *Registry) Gauge(opts Opts) *GaugeVec {
+	return &GaugeVec{family: r.mustRegister(opts, TypeGauge)}
+}
+
+// Histogram 获取或注册直方图
+func (r *Registry) Histogram(opts Opts) *HistogramVec {
+	if len(opts.Buckets) == 0 {
+		opts.Buckets = DefBuckets
+	}
+	if !sort.Float64sAreSorted(opts.Buckets) {
+		panic(fmt.Errorf("metric: histogram %s buckets must be sorted", opts.Name))
+	}
+
+	return &HistogramVec{family: r.mustRegister(opts, TypeHistogram)}
+}
+
+// Unregister 移除指标
+func (r *Registry) Unregister(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.families, sanitize(name))
+}
+
+// Register 注册采集器
+func (r *Registry) Register(collector Collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.collectors = append(r.collectors, collector)
+}
+
+// Gather 按名称排序的指标快照(含采集器指标)
+func (r *Registry) Gather() []Family {
+	r.mutex.RLock()
+	var (
+		list       = make([]*family, 0, len(r.families))
+		collectors = r.collectors
+	)
+	for _, f := range r.families {
+		list = append(list, f)
+	}
+	r.mutex.RUnlock()
+
+	var families = make([]Family, 0, len(list))
+	for _, f := range list {
+		families = append(families, f.gather())
+	}
+	for _, collector := range collectors {
+		families = append(families, collector.Collect()...)
+	}
+
+	sort.SliceStable(families, func(i, j int) bool {
+		return families[i].Name < families[j].Name
+	})
+
+	return families
+}
+
+func (r *Registry) mustRegister(opts Opts, typ Type) *family {
+	f, err := r.register(opts, typ)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func (r *Registry) register(opts Opts, typ Type) (*family, error) {
+	var labels = make([]string, len(opts.Labels))
+	for i, label := range opts.Labels {
+		labels[i] = sanitize(label)
+	}
+	opts.Name = sanitize(opts.Name)
+	opts.Labels = labels
+
+	r.mutex.RLock()
+	f, exists := r.families[opts.Name]
+	r.mutex.RUnlock()
+
+	if !exists {
+		var warn = r.warn(opts.Name)
+
+		r.mutex.Lock()
+		if f, exists = r.families[opts.Name]; !exists {
+			if limit, ok := r.limits[opts.Name]; ok {
+				opts.Limit = limit
+			}
+			f = &family{
+				opts:     opts,
+				typ:      typ,
+				warn:     warn,
+				children: make(map[uint64][]*child),
+			}
+			f.setLimit(opts.Limit)
+			r.families[opts.Name] = f
+		}
+		r.mutex.Unlock()
+	}
+
+	if f.typ != typ || !equalStrings(f.opts.Labels, opts.Labels) {
+		return nil, fmt.Errorf("metric: %s already registered with different type or labels", opts.Name)
+	}
+
+	return f, nil
+}
+
+// WithLabelValues 按标签值获取计数器, 值数量与标签不一致时panic
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	return c.family.get(values).(*Counter)
+}
+
+// With 按标签获取计数器, 缺少的标签为空字符串, 多余的标签忽略
+func (c *CounterVec) With(labels map[string]string) *Counter {
+	return c.WithLabelValues(c.family.values(labels)...)
+}
+
+// WithLabelValues 按标签值获取仪表盘
+func (g *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return g.family.get(values).(*Gauge)
+}
+
+// With 按标签获取仪表盘
+func (g *GaugeVec) With(labels map[string]string) *Gauge {
+	return g.WithLabelValues(g.family.values(labels)...)
+}
+
+// WithLabelValues 按标签值获取直方图
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return h.family.get(values).(*Histogram)
+}
+
+// With 按标签获取直方图
+func (h *HistogramVec) With(labels map[string]string) *Histogram {
+	return h.WithLabelValues(h.family.values(labels)...)
+}
+
+// 标签值的fnv-1a哈希, 避免拼接字符串分配内存
+func hashValues(values []string) uint64 {
+	var hash uint64 = 14695981039346656037
+	for _, value := range values {
+		for i := 0; i < len(value); i++ {
+			hash ^= uint64(value[i])
+			hash *= 1099511628211
+		}
+		hash ^= 0xff
+		hash *= 1099511628211
+	}
+	return hash
+}
+
+func (f *family) get(values []string) interface{} {
+	if len(values) != len(f.opts.Labels) {
+		panic(fmt.Errorf("metric: %s expected %d label values, got %d", f.opts.Name, len(f.opts.Labels), len(values)))
+	}
+
+	var l = f.limit.Load().(*limit)
+	if l.allow != nil {
+		values = f.filter(l, values)
+	}
+
+	var hash = hashValues(values)
+
+	f.mutex.RLock()
+	for _, c := range f.children[hash] {
+		if equalStrings(c.values, values) {
+			f.mutex.RUnlock()
+			return c.metric
+		}
+	}
+	f.mutex.RUnlock()
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, c := range f.children[hash] {
+		if equalStrings(c.values, values) {
+			return c.metric
+		}
+	}
+
+	if l.maxSeries > 0 && f.series >= l.maxSeries {
+		return f.overflow()
+	}
+	f.series++
+
+	return f.add(hash, values)
+}
+
+func (f *family) add(hash uint64, values []string) interface{} {
+	var c = &child{
+		values: append([]string(nil), values...),
+		metric: f.newMetric(),
+	}
+	f.children[hash] = append(f.children[hash], c)
+
+	return c.metric
+}
+
+func (f *family) newMetric() interface{} {
+	switch f.typ {
+	case TypeCounter:
+		return new(Counter)
+	case TypeGauge:
+		return new(Gauge)
+	}
+
+	return &Histogram{
+		upper:  f.opts.Buckets,
+		counts: make([]uint64, len(f.opts.Buckets)),
+	}
+}
+
+func (f *family) values(labels map[string]string) []string {
+	var values = make([]string, len(f.opts.Labels))
+	for i, label := range f.opts.Labels {
+		values[i] = labels[label]
+	}
+	return values
+}
+
+func (f *family) gather() Family {
+	var family = Family{
+		Name:   f.opts.Name,
+		Help:   f.opts.Help,
+		Type:   f.typ,
+		Labels: f.opts.Labels,
+	}
+
+	f.mutex.RLock()
+	for _, children := range f.children {
+		for _, c := range children {
+			var sample = Sample{Values: c.values}
+			switch m := c.metric.(type) {
+			case *Counter:
+				sample.Value = m.Value()
+			case *Gauge:
+				sample.Value = m.Value()
+			case *Histogram:
+				sample.Count, sample.Sum, sample.Buckets = m.snapshot()
+			}
+			family.Samples = append(family.Samples, sample)
+		}
+	}
+	f.mutex.RUnlock()
+
+	sort.Slice(family.Samples, func(i, j int) bool {
+		return lessStrings(family.Samples[i].Values, family.Samples[j].Values)
+	})
+
+	return family
+}
+
+// Inc 加1
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add 增加, 负数忽略
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	addFloat(&c.bits, delta)
+}
+
+// Value 当前值
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.bits))
+}
+
+// Set 设置
+func (g *Gauge) Set(value float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(value))
+}
+
+// Add 增加(可为负数)
+func (g *Gauge) Add(delta float64) {
+	addFloat(&g.bits, delta)
+}
+
+// Inc 加1
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec 减1
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Value 当前值
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+// Observe 观测一个值
+func (h *Histogram) Observe(value float64) {
+	// 桶数量较少, 二分查找第一个上界>=value的桶
+	if index := sort.SearchFloat64s(h.upper, value); index < len(h.upper) {
+		atomic.AddUint64(&h.counts[index], 1)
+	}
+	addFloat(&h.sum, value)
+	atomic.AddUint64(&h.count, 1)
+}
+
+// snapshot 观测次数、总和及累计桶
+func (h *Histogram) snapshot() (count uint64, sum float64, buckets []Bucket) {
+	count = atomic.LoadUint64(&h.count)
+	sum = math.Float64frombits(atomic.LoadUint64(&h.sum))
+
+	var total uint64
+	buckets = make([]Bucket, len(h.upper))
+	for i, upper := range h.upper {
+		total += atomic.LoadUint64(&h.counts[i])
+		buckets[i] = Bucket{Upper: upper, Count: total}
+	}
+
+	// 并发观测时保证+Inf桶不小于其他桶
+	if count < total {
+		count = total
+	}
+
+	return
+}
+
+func addFloat(bits *uint64, delta float64) {
+	for {
+		var old = atomic.LoadUint64(bits)
+		var value = math.Float64bits(math.Float64frombits(old) + delta)
+		if atomic.CompareAndSwapUint64(bits, old, value) {
+			return
+		}
+	}
+}
+
+// 指标名和标签名只保留[a-zA-Z0-9_:], 其他字符替换为下划线
+func sanitize(name string) string {
+	var valid = true
+	for i := 0; i < len(name); i++ {
+		if !validChar(name[i], i) {
+			valid = false
+			break
+		}
+	}
+	if valid && name != "" {
+		return name
+	}
+
+	var data = []byte(name)
+	for i := range data {
+		if !validChar(data[i], i) {
+			data[i] = '_'
+		}
+	}
+	if len(data) == 0 {
+		return "_"
+	}
+
+	return string(data)
+}
+
+func validChar(c byte, index int) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+		return true
+	case c >= '0' && c <= '9':
+		return index > 0
+	}
+	return false
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func lessStrings(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func (t Type) String() string {
+	switch t {
+	case TypeCounter:
+		return "counter"
+	case TypeGauge:
+		return "gauge"
+	}
+	return "histogram"
+}
@@ -0,0 +1,149 @@
+package metric
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	var registry = NewRegistry()
+
+	var counter = registry.Counter(Opts{Name: "requests-total", Help: "requests", Labels: []string{"method", "code"}})
+	counter.WithLabelValues("GET", "200").Inc()
+	counter.WithLabelValues("GET", "200").Add(2)
+	counter.WithLabelValues("GET", "200").Add(-1)
+	counter.With(map[string]string{"method": "POST", "other": "x"}).Inc()
+
+	// 同名获取同一指标
+	registry.Counter(Opts{Name: "requests_total", Labels: []string{"method", "code"}}).WithLabelValues("GET", "200").Inc()
+
+	var gauge = registry.Gauge(Opts{Name: "in_flight"})
+	gauge.WithLabelValues().Inc()
+	gauge.WithLabelValues().Inc()
+	gauge.WithLabelValues().Dec()
+	gauge.WithLabelValues().Add(0.5)
+
+	var histogram = registry.Histogram(Opts{Name: "latency", Labels: []string{"name"}, Buckets: []float64{0.1, 1}})
+	for _, value := range []float64{0.05, 0.1, 0.5, 2} {
+		histogram.WithLabelValues("a").Observe(value)
+	}
+
+	var families = registry.Gather()
+	if assert.Len(t, families, 3) {
+		assert.Equal(t, Family{
+			Name:   "in_flight",
+			Type:   TypeGauge,
+			Labels: []string{},
+			Samples: []Sample{
+				{Values: nil, Value: 1.5},
+			},
+		}, families[0])
+
+		assert.Equal(t, Family{
+			Name:   "latency",
+			Type:   TypeHistogram,
+			Labels: []string{"name"},
+			Samples: []Sample{
+				{Values: []string{"a"}, Count: 4, Sum: 2.65, Buckets: []Bucket{{0.1, 2}, {1, 3}}},
+			},
+		}, families[1])
+
+		assert.Equal(t, Family{
+			Name:   "requests_total",
+			Help:   "requests",
+			Type:   TypeCounter,
+			Labels: []string{"method", "code"},
+			Samples: []Sample{
+				{Values: []string{"GET", "200"}, Value: 4},
+				{Values: []string{"POST", ""}, Value: 1},
+			},
+		}, families[2])
+	}
+
+	assert.Panics(t, func() { registry.Gauge(Opts{Name: "latency"}) })
+	assert.Panics(t, func() { registry.Counter(Opts{Name: "requests_total"}) })
+	assert.Panics(t, func() { counter.WithLabelValues("GET") })
+	assert.Panics(t, func() { registry.Histogram(Opts{Name: "unsorted", Buckets: []float64{1, 0.1}}) })
+
+	registry.Unregister("latency")
+	assert.Len(t, registry.Gather(), 2)
+}
+
+func TestRegistry_Concurrent(t *testing.T) {
+	var (
+		wg        sync.WaitGroup
+		registry  = NewRegistry()
+		counter   = registry.Counter(Opts{Name: "counter", Labels: []string{"worker"}})
+		histogram = registry.Histogram(Opts{Name: "histogram"})
+	)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				counter.WithLabelValues("worker").Inc()
+				histogram.WithLabelValues().Observe(0.01)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, float64(8000), counter.WithLabelValues("worker").Value())
+
+	count, sum, buckets := histogram.WithLabelValues().snapshot()
+	assert.Equal(t, uint64(8000), count)
+	assert.InDelta(t, 80, sum, 1e-6)
+	assert.Equal(t, uint64(8000), buckets[len(buckets)-1].Count)
+}
+
+func TestRegistry_Allocs(t *testing.T) {
+	var counter = NewRegistry().Counter(Opts{Name: "counter", Labels: []string{"a", "b"}})
+	counter.WithLabelValues("x", "y").Inc()
+
+	assert.Equal(t, float64(0), testing.AllocsPerRun(100, func() {
+		counter.WithLabelValues("x", "y").Inc()
+	}))
+}
+
+func TestBuckets(t *testing.T) {
+	assert.Equal(t, []float64{1, 3, 5}, LinearBuckets(1, 2, 3))
+	assert.Equal(t, []float64{1, 2, 4}, ExponentialBuckets(1, 2, 3))
+}
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "zrpc::hedge", sanitize("zrpc::hedge"))
+	assert.Equal(t, "http_requests_total", sanitize("http.requests-total"))
+	assert.Equal(t, "_xx", sanitize("1xx"))
+	assert.Equal(t, "_", sanitize(""))
+}
+
+func TestRpc(t *testing.T) {
+	Rpc("test", "/caller", "/callee", 200, 30*time.Millisecond, nil)
+	Rpc("test", "/caller", "/callee", 200, 2*time.Second, nil)
+
+	assert.Equal(t, float64(2), rpcRequests.WithLabelValues("test", "/caller", "/callee", "200").Value())
+
+	count, sum, buckets := rpcLatency.WithLabelValues("test", "/caller", "/callee", "200").snapshot()
+	assert.Equal(t, uint64(2), count)
+	assert.InDelta(t, 2.03, sum, 1e-9)
+	assert.Equal(t, Bucket{Upper: 0.05, Count: 1}, buckets[3])
+	assert.Equal(t, Bucket{Upper: 2.5, Count: 2}, buckets[8])
+}
+
+func TestCount(t *testing.T) {
+	Count("test::count", 1, map[string]interface{}{"errno": 1, "message.text": "failed"})
+	Count("test::count", 2, map[string]interface{}{"errno": 1, "message.text": "failed", "extra": true})
+	Count("test::count", 1, nil)
+
+	var counter = Default.Counter(Opts{Name: "test::count_total", Labels: []string{"errno", "message_text"}})
+	assert.Equal(t, float64(3), counter.WithLabelValues("1", "failed").Value())
+	assert.Equal(t, float64(1), counter.WithLabelValues("", "").Value())
+
+	// 同名冲突时丢弃
+	Default.Gauge(Opts{Name: "test::conflict_total"})
+	assert.NotPanics(t, func() { Count("test::conflict", 1, nil) })
+}
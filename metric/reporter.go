@@ -0,0 +1,106 @@
+package metric
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Reporter 主动上报指标(用于无法被拉取的批处理任务等)
+type Reporter interface {
+	Report(ctx context.Context, families []Family) error
+}
+
+// ReportOption 定期上报选项, 零值使用默认值
+type ReportOption struct {
+	Interval time.Duration // 上报间隔, 默认10s
+	Timeout  time.Duration // 单次上报超时, 默认5s
+	OnError  func(err error)
+}
+
+// Scheduler 定期从注册表采集并上报
+type Scheduler struct {
+	mutex    sync.Mutex
+	option   ReportOption
+	registry *Registry
+	reporter Reporter
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewScheduler 创建并启动定期上报, registry为nil时使用Default
+func NewScheduler(registry *Registry, reporter Reporter, option ReportOption) *Scheduler {
+	if registry == nil {
+		registry = Default
+	}
+	if option.Interval <= 0 {
+		option.Interval = 10 * time.Second
+	}
+	if option.Timeout <= 0 {
+		option.Timeout = 5 * time.Second
+	}
+
+	var s = &Scheduler{
+		option:   option,
+		registry: registry,
+		reporter: reporter,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *Scheduler) run() {
+	var ticker = time.NewTicker(s.option.Interval)
+	defer ticker.Stop()
+	defer close(s.done)
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), s.option.Timeout)
+			if err := s.Flush(ctx); err != nil && s.option.OnError != nil {
+				s.option.OnError(err)
+			}
+			cancel()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Flush 立即上报一次
+func (s *Scheduler) Flush(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.reporter.Report(ctx, s.registry.Gather())
+}
+
+// Shutdown 停止定期上报, 上报最后一次并关闭上报器(实现io.Closer时)
+func (s *Scheduler) Shutdown(ctx context.Context) (err error) {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	err = s.Flush(ctx)
+
+	if closer, ok := s.reporter.(io.Closer); ok {
+		if e := closer.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return
+}
@@ -0,0 +1,95 @@
+package metric
+
+import (
+	"runtime"
+	"time"
+)
+
+// GoCollector Go运行时指标采集器
+type GoCollector struct{}
+
+// ProcessCollector 进程指标采集器, 仅支持linux(读取/proc)
+type ProcessCollector struct {
+	pid int // 0为当前进程
+}
+
+// NewGoCollector 创建Go运行时指标采集器
+func NewGoCollector() *GoCollector {
+	return new(GoCollector)
+}
+
+// NewProcessCollector 创建当前进程指标采集器
+func NewProcessCollector() *ProcessCollector {
+	return new(ProcessCollector)
+}
+
+func (g *GoCollector) Collect() []Family {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	threads, _ := runtime.ThreadCreateProfile(nil)
+
+	return []Family{
+		gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine())),
+		gauge("go_threads", "Number of OS threads created.", float64(threads)),
+		{
+			Name:    "go_info",
+			Help:    "Information about the Go environment.",
+			Type:    TypeGauge,
+			Labels:  []string{"version"},
+			Samples: []Sample{{Values: []string{runtime.Version()}, Value: 1}},
+		},
+		counter("go_gc_cycles_total", "Number of completed GC cycles.", float64(stats.NumGC)),
+		counter("go_gc_pause_seconds_total", "Total GC pause time in seconds.", float64(stats.PauseTotalNs)/float64(time.Second)),
+		gauge("go_gc_last_time_seconds", "Unix time of the last GC in seconds.", float64(stats.LastGC)/float64(time.Second)),
+		gauge("go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", float64(stats.HeapAlloc)),
+		gauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", float64(stats.HeapInuse)),
+		gauge("go_memstats_heap_idle_bytes", "Number of heap bytes waiting to be used.", float64(stats.HeapIdle)),
+		gauge("go_memstats_heap_sys_bytes", "Number of heap bytes obtained from system.", float64(stats.HeapSys)),
+		gauge("go_memstats_heap_objects", "Number of allocated objects.", float64(stats.HeapObjects)),
+		gauge("go_memstats_stack_inuse_bytes", "Number of bytes in use by the stack allocator.", float64(stats.StackInuse)),
+		gauge("go_memstats_sys_bytes", "Number of bytes obtained from system.", float64(stats.Sys)),
+		gauge("go_memstats_next_gc_bytes", "Number of heap bytes when next garbage collection will take place.", float64(stats.NextGC)),
+		counter("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", float64(stats.TotalAlloc)),
+		counter("go_memstats_mallocs_total", "Total number of mallocs.", float64(stats.Mallocs)),
+		counter("go_memstats_frees_total", "Total number of frees.", float64(stats.Frees)),
+	}
+}
+
+func (p *ProcessCollector) Collect() []Family {
+	stat, err := readProcess(p.pid)
+	if err != nil {
+		return nil
+	}
+
+	var families = []Family{
+		counter("process_cpu_seconds_total", "Total user and system CPU time spent in seconds.", stat.cpu),
+		gauge("process_open_fds", "Number of open file descriptors.", float64(stat.openFDs)),
+		gauge("process_resident_memory_bytes", "Resident memory size in bytes.", float64(stat.rss)),
+		gauge("process_virtual_memory_bytes", "Virtual memory size in bytes.", float64(stat.vsize)),
+		gauge("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", stat.start),
+	}
+	if stat.maxFDs > 0 {
+		families = append(families, gauge("process_max_fds", "Maximum number of open file descriptors.", float64(stat.maxFDs)))
+	}
+
+	return families
+}
+
+// 进程状态
+type processStat struct {
+	cpu     float64 // cpu秒数
+	openFDs int
+	maxFDs  int
+	rss     int64
+	vsize   int64
+	start   float64 // 启动时间(unix秒)
+}
+
+func gauge(name, help string, value float64) Family {
+	return Family{Name: name, Help: help, Type: TypeGauge, Samples: []Sample{{Value: value}}}
+}
+
+func counter(name, help string, value float64) Family {
+	return Family{Name: name, Help: help, Type: TypeCounter, Samples: []Sample{{Value: value}}}
+}
@@ -0,0 +1,49 @@
+package metric
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func families(list []Family) map[string]Family {
+	var families = make(map[string]Family)
+	for _, family := range list {
+		families[family.Name] = family
+	}
+	return families
+}
+
+func TestGoCollector(t *testing.T) {
+	runtime.GC()
+
+	var list = families(NewGoCollector().Collect())
+	assert.True(t, list["go_goroutines"].Samples[0].Value >= 1)
+	assert.True(t, list["go_gc_cycles_total"].Samples[0].Value >= 1)
+	assert.True(t, list["go_memstats_heap_alloc_bytes"].Samples[0].Value > 0)
+	assert.Equal(t, []string{runtime.Version()}, list["go_info"].Samples[0].Values)
+	assert.Equal(t, TypeCounter, list["go_memstats_alloc_bytes_total"].Type)
+}
+
+func TestProcessCollector(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		assert.Nil(t, NewProcessCollector().Collect())
+		return
+	}
+
+	file, err := os.Open(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var list = families(NewProcessCollector().Collect())
+	assert.True(t, list["process_open_fds"].Samples[0].Value >= 1)
+	assert.True(t, list["process_max_fds"].Samples[0].Value >= list["process_open_fds"].Samples[0].Value)
+	assert.True(t, list["process_resident_memory_bytes"].Samples[0].Value > 0)
+	assert.True(t, list["process_virtual_memory_bytes"].Samples[0].Value > 0)
+	assert.True(t, list["process_start_time_seconds"].Samples[0].Value > 1e9)
+	assert.True(t, list["process_cpu_seconds_total"].Samples[0].Value >= 0)
+}
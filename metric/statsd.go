@@ -0,0 +1,214 @@
+package metric
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsDOption StatsD上报选项
+type StatsDOption struct {
+	Addr       string            // UDP地址, 默认127.0.0.1:8125
+	Prefix     string            // 指标名前缀
+	DogStatsD  bool              // 使用DogStatsD标签(|#k:v), 否则使用Graphite标签(name;k=v)
+	Tags       map[string]string // 全局标签
+	MaxPacket  int               // 单个UDP包最大字节数, 默认1432
+	SkipBucket bool              // 不上报直方图桶
+}
+
+// StatsD StatsD/DogStatsD UDP上报器, 计数器按两次上报的差值发送
+type StatsD struct {
+	mutex  sync.Mutex
+	option StatsDOption
+	conn   net.Conn
+	last   map[string]float64
+	tags   []string
+}
+
+type statsdSeries struct {
+	name  string
+	tags  []string
+	value float64
+}
+
+// NewStatsD 创建StatsD上报器
+func NewStatsD(option StatsDOption) (*StatsD, error) {
+	if option.Addr == "" {
+		option.Addr = "127.0.0.1:8125"
+	}
+	if option.MaxPacket <= 0 {
+		option.MaxPacket = 1432
+	}
+
+	conn, err := net.Dial("udp", option.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var s = &StatsD{
+		option: option,
+		conn:   conn,
+		last:   make(map[string]float64),
+	}
+	for key, value := range option.Tags {
+		s.tags = append(s.tags, sanitize(key), value)
+	}
+
+	return s, nil
+}
+
+func (s *StatsD) Report(ctx context.Context, families []Family) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.conn.SetWriteDeadline(deadline)
+	} else {
+		_ = s.conn.SetWriteDeadline(time.Time{})
+	}
+
+	var packet bytes.Buffer
+	var write = func(line string) error {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > s.option.MaxPacket {
+			if _, err := s.conn.Write(packet.Bytes()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+		return nil
+	}
+
+	for _, family := range families {
+		for _, sample := range family.Samples {
+			var tags = s.sampleTags(family.Labels, sample.Values)
+
+			switch family.Type {
+			case TypeCounter:
+				if delta, ok := s.delta(family.Name, tags, sample.Value); ok {
+					if err := write(s.line(family.Name, formatFloat(delta), "c", tags)); err != nil {
+						return err
+					}
+				}
+			case TypeGauge:
+				if err := write(s.line(family.Name, formatFloat(sample.Value), "g", tags)); err != nil {
+					return err
+				}
+			case TypeHistogram:
+				if err := s.histogram(family.Name, sample, tags, write); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if packet.Len() > 0 {
+		if _, err := s.conn.Write(packet.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 直方图以计数器上报观测次数、总和及各桶数量(桶以le标签区分)
+func (s *StatsD) histogram(name string, sample Sample, tags []string, write func(string) error) error {
+	var series = []statsdSeries{
+		{name + "_count", tags, float64(sample.Count)},
+		{name + "_sum", tags, sample.Sum},
+	}
+
+	if !s.option.SkipBucket {
+		for _, bucket := range sample.Buckets {
+			var le = append(tags[:len(tags):len(tags)], "le", formatFloat(bucket.Upper))
+			series = append(series, statsdSeries{name + "_bucket", le, float64(bucket.Count)})
+		}
+	}
+
+	for _, item := range series {
+		if delta, ok := s.delta(item.name, item.tags, item.value); ok {
+			if err := write(s.line(item.name, formatFloat(delta), "c", item.tags)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// 计数器差值, 无变化时不上报, 计数器重置时上报当前值
+func (s *StatsD) delta(name string, tags []string, value float64) (float64, bool) {
+	var key = name + "\xff" + strings.Join(tags, "\xff")
+
+	var last, exists = s.last[key]
+	s.last[key] = value
+
+	if value < last {
+		return value, value > 0
+	}
+	if exists && value == last || value == 0 {
+		return 0, false
+	}
+
+	return value - last, true
+}
+
+func (s *StatsD) sampleTags(labels, values []string) []string {
+	var tags = make([]string, 0, len(s.tags)+len(labels)*2)
+	tags = append(tags, s.tags...)
+	for i, label := range labels {
+		tags = append(tags, label, values[i])
+	}
+	return tags
+}
+
+// 格式: name:value|type|#k:v 或 name;k=v:value|type
+func (s *StatsD) line(name, value, typ string, tags []string) string {
+	var builder strings.Builder
+
+	builder.WriteString(s.option.Prefix)
+	builder.WriteString(name)
+
+	if !s.option.DogStatsD {
+		for i := 0; i < len(tags); i += 2 {
+			builder.WriteString(";" + tags[i] + "=" + statsdEscape(tags[i+1], ";:|=\n "))
+		}
+	}
+
+	builder.WriteString(":" + value + "|" + typ)
+
+	if s.option.DogStatsD && len(tags) > 0 {
+		builder.WriteString("|#")
+		for i := 0; i < len(tags); i += 2 {
+			if i > 0 {
+				builder.WriteByte(',')
+			}
+			builder.WriteString(tags[i] + ":" + statsdEscape(tags[i+1], ",|#\n"))
+		}
+	}
+
+	return builder.String()
+}
+
+// 替换标签值中的分隔符
+func statsdEscape(value, chars string) string {
+	if !strings.ContainsAny(value, chars) {
+		return value
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(chars, r) {
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
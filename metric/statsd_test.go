@@ -0,0 +1,111 @@
+package metric
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 本地UDP监听, 返回地址和读取下一个包的函数
+func listenUDP(t *testing.T) (string, func() []string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn.LocalAddr().String(), func() []string {
+		var buf = make([]byte, 65536)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return nil
+		}
+		var lines = strings.Split(string(buf[:n]), "\n")
+		sort.Strings(lines)
+		return lines
+	}
+}
+
+func TestStatsD(t *testing.T) {
+	addr, read := listenUDP(t)
+
+	var registry = NewRegistry()
+	var requests = registry.Counter(Opts{Name: "requests_total", Labels: []string{"code"}})
+	var latency = registry.Histogram(Opts{Name: "latency", Buckets: []float64{1}})
+	requests.WithLabelValues("200").Add(3)
+	registry.Gauge(Opts{Name: "in_flight"}).WithLabelValues().Set(2)
+	latency.WithLabelValues().Observe(0.5)
+
+	statsd, err := NewStatsD(StatsDOption{Addr: addr, Prefix: "app.", Tags: map[string]string{"env": "test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer statsd.Close()
+
+	assert.NoError(t, statsd.Report(context.Background(), registry.Gather()))
+	assert.Equal(t, []string{
+		"app.in_flight;env=test:2|g",
+		"app.latency_bucket;env=test;le=1:1|c",
+		"app.latency_count;env=test:1|c",
+		"app.latency_sum;env=test:0.5|c",
+		"app.requests_total;env=test;code=200:3|c",
+	}, read())
+
+	// 计数器按差值上报, 无变化不上报
+	requests.WithLabelValues("200").Add(2)
+	assert.NoError(t, statsd.Report(context.Background(), registry.Gather()))
+	assert.Equal(t, []string{
+		"app.in_flight;env=test:2|g",
+		"app.requests_total;env=test;code=200:2|c",
+	}, read())
+}
+
+func TestDogStatsD(t *testing.T) {
+	addr, read := listenUDP(t)
+
+	var registry = NewRegistry()
+	registry.Counter(Opts{Name: "requests_total", Labels: []string{"path", "code"}}).WithLabelValues("/a,b|c", "200").Inc()
+	registry.Histogram(Opts{Name: "latency"}).WithLabelValues().Observe(0.5)
+
+	statsd, err := NewStatsD(StatsDOption{Addr: addr, DogStatsD: true, SkipBucket: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer statsd.Close()
+
+	assert.NoError(t, statsd.Report(context.Background(), registry.Gather()))
+	assert.Equal(t, []string{
+		"latency_count:1|c",
+		"latency_sum:0.5|c",
+		"requests_total:1|c|#path:/a_b_c,code:200",
+	}, read())
+}
+
+func TestStatsD_Packet(t *testing.T) {
+	addr, read := listenUDP(t)
+
+	var (
+		registry = NewRegistry()
+		gauge    = registry.Gauge(Opts{Name: "gauge", Labels: []string{"index"}})
+	)
+	for _, index := range []string{"1", "2", "3", "4"} {
+		gauge.WithLabelValues(index).Set(1)
+	}
+
+	// 每个包最多放下两行
+	statsd, err := NewStatsD(StatsDOption{Addr: addr, DogStatsD: true, MaxPacket: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer statsd.Close()
+
+	assert.NoError(t, statsd.Report(context.Background(), registry.Gather()))
+	assert.Equal(t, []string{"gauge:1|g|#index:1", "gauge:1|g|#index:2"}, read())
+	assert.Equal(t, []string{"gauge:1|g|#index:3", "gauge:1|g|#index:4"}, read())
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/gin-gonic/gin"
@@ -27,7 +28,7 @@ var (
 	errnoMutex sync.Mutex
 )
 
-// RegisterErrorCode 错误码与http状态码映射
+// RegisterErrorCode 错误码与http状态码映射, 同一错误码不能重复注册
 func RegisterErrorCode(codes map[int]int) {
 	errnoMutex.Lock()
 	defer errnoMutex.Unlock()
@@ -47,17 +48,17 @@ func NewResponse(data interface{}, err error) (resp Response) {
 		errno := errors.New(errors.UnknownError, err)
 
 		resp.Errno = errno.Errno()
+		resp.Message = errno.Message()
 
 		if err = errno.Unwrap(); err != nil {
 			resp.Data = err.Error()
 		}
 	} else {
 		resp.Errno = errors.Success
+		resp.Message = errors.Msg(resp.Errno)
 		resp.Data = data
 	}
 
-	resp.Message = errors.Msg(resp.Errno)
-
 	return
 }
 
@@ -83,6 +84,61 @@ func (c Controller) Bind(ctx *gin.Context, v interface{}) (err error) {
 	return
 }
 
+// ETag 版本号对应的ETag值
+func ETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// 解析ETag中的版本号, 兼容弱校验W/前缀
+func parseETag(tag string) (int64, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	if unquoted, err := strconv.Unquote(tag); err == nil {
+		tag = unquoted
+	}
+	return strconv.ParseInt(tag, 10, 64)
+}
+
+// SetETag 以资源版本号设置ETag响应头
+func (c Controller) SetETag(ctx *gin.Context, version int64) {
+	ctx.Header("ETag", ETag(version))
+}
+
+// IfMatch 解析If-Match请求头中的版本号, 用于Dao.UpdateWithVersion; 未设置或为*时ok为false
+func (c Controller) IfMatch(ctx *gin.Context) (version int64, ok bool, err error) {
+	var header = strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return
+	}
+
+	if strings.Contains(header, ",") {
+		return 0, false, errors.New(errors.InvalidRequest, fmt.Errorf("If-Match: multiple etags"))
+	}
+
+	if version, err = parseETag(header); err != nil {
+		return 0, false, errors.New(errors.InvalidRequest, fmt.Errorf("If-Match: invalid etag %s", header))
+	}
+
+	return version, true, nil
+}
+
+// NotModified 设置ETag, If-None-Match与版本号一致时响应304并返回true
+func (c Controller) NotModified(ctx *gin.Context, version int64) bool {
+	c.SetETag(ctx, version)
+
+	for _, tag := range strings.Split(ctx.GetHeader("If-None-Match"), ",") {
+		if tag = strings.TrimSpace(tag); tag == "*" {
+			ctx.AbortWithStatus(http.StatusNotModified)
+			return true
+		}
+		if v, err := parseETag(tag); err == nil && v == version {
+			ctx.AbortWithStatus(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
+
 func (c Controller) Response(ctx *gin.Context, data interface{}, err error) {
 	if err != nil {
 		errno := errors.New(errors.UnknownError, err)
@@ -0,0 +1,351 @@
+package micro
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/zooyer/miskit/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Cursor 游标分页信息
+type Cursor struct {
+	Total int64  `json:"total,omitempty"`       // 总数, NoTotal时不统计
+	Next  string `json:"next_cursor,omitempty"` // 下一页游标, 作为after传入, 为空表示没有下一页
+	Prev  string `json:"prev_cursor,omitempty"` // 上一页游标, 作为before传入, 为空表示没有上一页
+}
+
+// CursorResult 游标分页结果
+type CursorResult struct {
+	Query
+	Cursor
+	Count int         `json:"count"`
+	Data  interface{} `json:"data"`
+}
+
+// 排序字段
+type sortKey struct {
+	field *schema.Field
+	desc  bool
+}
+
+// 游标内容, 记录排序方式避免不同排序间误用
+type cursorValue struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// 解析排序, 格式为 字段 [ASC|DESC], 多个字段以逗号分隔, 字段需在模型白名单中
+func parseSort(sort string, s *schema.Schema) (keys []sortKey, err error) {
+	var columns = Columns(s)
+
+	for _, item := range strings.Split(sort, ",") {
+		var parts = strings.Fields(item)
+		if len(parts) == 0 {
+			continue
+		}
+		if len(parts) > 2 {
+			return nil, fmt.Errorf("sort: invalid %q", item)
+		}
+
+		var key sortKey
+		if len(parts) == 2 {
+			switch strings.ToUpper(parts[1]) {
+			case "ASC":
+			case "DESC":
+				key.desc = true
+			default:
+				return nil, fmt.Errorf("sort: invalid direction %q", parts[1])
+			}
+		}
+
+		name, exists := columns[parts[0]]
+		if !exists {
+			return nil, fmt.Errorf("sort: unknown field %q", parts[0])
+		}
+		key.field = s.LookUpField(name)
+		keys = append(keys, key)
+	}
+
+	return
+}
+
+// 游标分页的排序, 末尾追加主键保证顺序唯一, 方向与第一个排序字段一致
+func cursorKeys(sort string, s *schema.Schema) ([]sortKey, error) {
+	keys, err := parseSort(sort, s)
+	if err != nil {
+		return nil, err
+	}
+
+	var primary = s.PrioritizedPrimaryField
+	if primary == nil {
+		return nil, fmt.Errorf("cursor: %s has no primary key", s.Table)
+	}
+
+	// NULL不参与比较, 键集条件会漏掉记录
+	for _, key := range keys {
+		if nullable(key.field) {
+			return nil, fmt.Errorf("cursor: sort field %q is nullable", key.field.Name)
+		}
+	}
+
+	for _, key := range keys {
+		if key.field == primary {
+			return keys, nil
+		}
+	}
+
+	var desc bool
+	if len(keys) > 0 {
+		desc = keys[0].desc
+	}
+
+	return append(keys, sortKey{field: primary, desc: desc}), nil
+}
+
+func sortString(keys []sortKey) string {
+	var list = make([]string, len(keys))
+	for i, key := range keys {
+		list[i] = key.field.DBName
+		if key.desc {
+			list[i] += " DESC"
+		}
+	}
+	return strings.Join(list, ",")
+}
+
+func orderBy(keys []sortKey, reverse bool) clause.OrderBy {
+	var orderBy clause.OrderBy
+	for _, key := range keys {
+		orderBy.Columns = append(orderBy.Columns, clause.OrderByColumn{
+			Column: clause.Column{Name: key.field.DBName},
+			Desc:   key.desc != reverse,
+		})
+	}
+	return orderBy
+}
+
+func encodeCursor(keys []sortKey, values []interface{}) string {
+	var raw = make([]json.RawMessage, len(values))
+	for i, value := range values {
+		raw[i], _ = json.Marshal(value)
+	}
+
+	data, _ := json.Marshal(cursorValue{Sort: sortString(keys), Values: raw})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// 解码游标, 值按排序字段的类型还原(如time.Time), keys为nil时只校验格式
+func decodeCursor(cursor string, keys []sortKey) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("cursor: invalid encoding")
+	}
+
+	var value cursorValue
+	if err = json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("cursor: invalid content")
+	}
+
+	if keys == nil {
+		return nil, nil
+	}
+
+	if value.Sort != sortString(keys) || len(value.Values) != len(keys) {
+		return nil, fmt.Errorf("cursor: sort mismatch")
+	}
+
+	var values = make([]interface{}, len(keys))
+	for i, key := range keys {
+		if bytes.Equal(bytes.TrimSpace(value.Values[i]), []byte("null")) {
+			return nil, fmt.Errorf("cursor: invalid content")
+		}
+
+		var v = reflect.New(key.field.FieldType)
+		if err = json.Unmarshal(value.Values[i], v.Interface()); err != nil {
+			return nil, fmt.Errorf("cursor: invalid content")
+		}
+		values[i] = v.Elem().Interface()
+	}
+
+	return values, nil
+}
+
+// 字段可以为NULL(指针、sql.NullXxx、gorm.DeletedAt等)
+func nullable(field *schema.Field) bool {
+	var typ = field.FieldType
+	if typ.Kind() == reflect.Ptr {
+		return true
+	}
+	if typ.Kind() == reflect.Struct {
+		if valid, ok := typ.FieldByName("Valid"); ok && valid.Type.Kind() == reflect.Bool {
+			return true
+		}
+	}
+	return false
+}
+
+// 键集条件: (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., before时比较方向相反
+func keyset(keys []sortKey, values []interface{}, before bool) clause.Expression {
+	var or = make([]clause.Expression, 0, len(keys))
+	for i, key := range keys {
+		var and = make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			and = append(and, clause.Eq{Column: clause.Column{Name: keys[j].field.DBName}, Value: values[j]})
+		}
+
+		var column = clause.Column{Name: key.field.DBName}
+		if key.desc != before {
+			and = append(and, clause.Lt{Column: column, Value: values[i]})
+		} else {
+			and = append(and, clause.Gt{Column: column, Value: values[i]})
+		}
+		or = append(or, clause.And(and...))
+	}
+	return clause.Or(or...)
+}
+
+// 是否游标分页
+func (q *Query) cursor() bool {
+	return q.After != "" || q.Before != ""
+}
+
+// ByCursor 游标分页条件, after取游标之后的记录, before取游标之前的记录(逆序查询, 由Dao还原顺序)
+func (q *Query) ByCursor(db *gorm.DB) *gorm.DB {
+	if !q.cursor() {
+		return db
+	}
+
+	s, err := modelSchema(db)
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+
+	keys, err := cursorKeys(q.Sort, s)
+	if err != nil {
+		_ = db.AddError(errors.New(errors.InvalidRequest, err))
+		return db
+	}
+
+	var cursor = q.After
+	if q.Before != "" {
+		cursor = q.Before
+	}
+
+	values, err := decodeCursor(cursor, keys)
+	if err != nil {
+		_ = db.AddError(errors.New(errors.InvalidRequest, err))
+		return db
+	}
+
+	return db.Where(keyset(keys, values, q.Before != ""))
+}
+
+// ListCursor 游标分页查询, 多查一条判断是否有下一页, NoTotal时不统计总数
+func (d Dao) ListCursor(ctx context.Context, query Query, equal Equal, out interface{}) (cursor Cursor, err error) {
+	var db = d.DB(ctx)
+	if err = db.Statement.Parse(d.model); err != nil {
+		return
+	}
+
+	keys, err := cursorKeys(query.Sort, db.Statement.Schema)
+	if err != nil {
+		return cursor, errors.New(errors.InvalidRequest, err)
+	}
+
+	// 游标需要排序字段的值
+	if len(query.Select) > 0 {
+		var selected = append([]string(nil), query.Select...)
+		for _, key := range keys {
+			selected = append(selected, key.field.DBName)
+		}
+		query.Select = selected
+	}
+	query.Sort = sortString(keys)
+
+	var size = query.Size
+	if size > 0 {
+		query.Size++
+	}
+
+	if cursor.Total, err = d.list(ctx, query, equal, out); err != nil {
+		return
+	}
+
+	var rows = reflect.ValueOf(out).Elem()
+	var more = size > 0 && rows.Len() > size
+	if more {
+		rows.Set(rows.Slice(0, size))
+	}
+	if query.Before != "" {
+		reverse(rows)
+	}
+
+	if rows.Len() == 0 {
+		return
+	}
+
+	var (
+		first = cursorOf(ctx, keys, rows.Index(0))
+		last  = cursorOf(ctx, keys, rows.Index(rows.Len()-1))
+	)
+
+	switch {
+	case query.Before != "":
+		cursor.Next = last
+		if more {
+			cursor.Prev = first
+		}
+	default:
+		if more {
+			cursor.Next = last
+		}
+		if query.After != "" {
+			cursor.Prev = first
+		}
+	}
+
+	return
+}
+
+func cursorOf(ctx context.Context, keys []sortKey, row reflect.Value) string {
+	row = reflect.Indirect(row)
+
+	var values = make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i], _ = key.field.ValueOf(ctx, row)
+	}
+
+	return encodeCursor(keys, values)
+}
+
+func reverse(rows reflect.Value) {
+	var swap = reflect.Swapper(rows.Interface())
+	for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
+
+func modelSchema(db *gorm.DB) (*schema.Schema, error) {
+	var model = db.Statement.Model
+	if model == nil {
+		model = db.Statement.Dest
+	}
+	if model == nil {
+		return nil, fmt.Errorf("micro: model not set")
+	}
+
+	if err := db.Statement.Parse(model); err != nil {
+		return nil, err
+	}
+
+	return db.Statement.Schema, nil
+}
@@ -0,0 +1,209 @@
+package micro
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zooyer/miskit/errors"
+	"gorm.io/gorm"
+)
+
+func newCursorDao(t *testing.T) Dao {
+	var db = newFilterDB(t)
+
+	// 5条status相同的记录, 验证主键作为排序的补充
+	for _, name := range []string{"dave", "erin", "frank", "grace", "heidi"} {
+		if err := db.Create(&filterModel{Name: name, Status: 2}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return NewDao(func(ctx context.Context) *gorm.DB {
+		return db.WithContext(ctx)
+	}, new(filterModel))
+}
+
+func modelNames(list []filterModel) []string {
+	var names = make([]string, len(list))
+	for i, m := range list {
+		names[i] = m.Name
+	}
+	return names
+}
+
+func TestDao_ListCursor(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		dao   = newCursorDao(t)
+		query = Query{Size: 3, Sort: "status DESC"}
+		pages [][]string
+	)
+
+	// 主键排序方向与第一个排序字段一致
+	var expect = [][]string{
+		{"alan", "heidi", "grace"},
+		{"frank", "erin", "dave"},
+		{"bob", "carol", "alice"},
+	}
+
+	// 向后翻页
+	var cursor Cursor
+	for {
+		var list []filterModel
+		page, err := dao.ListCursor(ctx, query, nil, &list)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if len(pages) == 0 {
+			assert.Equal(t, int64(9), page.Total)
+			assert.Empty(t, page.Prev)
+		} else {
+			assert.NotEmpty(t, page.Prev)
+		}
+
+		pages = append(pages, modelNames(list))
+		if cursor = page; page.Next == "" {
+			break
+		}
+		query.After = page.Next
+	}
+	assert.Equal(t, expect, pages)
+
+	// 向前翻页
+	query.After, query.Before, query.NoTotal = "", cursor.Prev, true
+	var list []filterModel
+	page, err := dao.ListCursor(ctx, query, nil, &list)
+	assert.NoError(t, err)
+	assert.Equal(t, expect[1], modelNames(list))
+	assert.Equal(t, int64(0), page.Total)
+	assert.NotEmpty(t, page.Prev)
+	assert.NotEmpty(t, page.Next)
+
+	query.Before = page.Prev
+	page, err = dao.ListCursor(ctx, query, nil, &list)
+	assert.NoError(t, err)
+	assert.Equal(t, expect[0], modelNames(list))
+	assert.Empty(t, page.Prev)
+
+	// List同样支持before, 结果为正序
+	list = nil
+	_, err = dao.List(ctx, query, nil, &list)
+	assert.NoError(t, err)
+	assert.Equal(t, expect[0], modelNames(list))
+
+	// 游标与排序不一致
+	query.Sort = "name"
+	_, err = dao.ListCursor(ctx, query, nil, &list)
+	assert.True(t, errors.Is(err, errors.InvalidRequest), err)
+}
+
+func TestDao_ListCursor_Select(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		dao   = newCursorDao(t)
+		query = Query{Size: 2, Sort: "name", Select: []string{"name"}, Where: "status:eq:2"}
+		list  []filterModel
+	)
+
+	page, err := dao.ListCursor(ctx, query, Equal{"deleted_at": 0}, &list)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob", "dave"}, modelNames(list))
+	assert.Equal(t, int64(6), page.Total)
+
+	query.After = page.Next
+	page, err = dao.ListCursor(ctx, query, nil, &list)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"erin", "frank"}, modelNames(list))
+}
+
+type cursorModel struct {
+	Model
+	Name  string    `json:"name"`
+	At    time.Time `json:"at"`
+	Score *int      `json:"score"`
+}
+
+func (cursorModel) TableName() string {
+	return "cursor"
+}
+
+func TestDao_ListCursor_Type(t *testing.T) {
+	db, err := OpenDB("sqlite", ":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(new(cursorModel)); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ctx   = context.Background()
+		start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		dao   = NewTypedDao[cursorModel](func(ctx context.Context) *gorm.DB {
+			return db.WithContext(ctx)
+		})
+	)
+	for i, name := range []string{"e", "d", "c", "b", "a"} {
+		assert.NoError(t, dao.Create(ctx, &cursorModel{Name: name, At: start.Add(time.Duration(i) * time.Minute)}))
+	}
+
+	// 时间字段按time.Time比较, 不按字符串比较
+	var (
+		query = Query{Size: 2, Sort: "at"}
+		pages [][]string
+	)
+	for {
+		list, cursor, err := dao.ListCursor(ctx, query, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var names []string
+		for _, m := range list {
+			names = append(names, m.Name)
+		}
+		pages = append(pages, names)
+
+		if query.After = cursor.Next; cursor.Next == "" {
+			break
+		}
+	}
+	assert.Equal(t, [][]string{{"e", "d"}, {"c", "b"}, {"a"}}, pages)
+
+	// 可为NULL的字段不能作为游标排序
+	_, _, err = dao.ListCursor(ctx, Query{Size: 2, Sort: "score"}, nil)
+	assert.True(t, errors.Is(err, errors.InvalidRequest), err)
+
+	// 普通分页不受影响
+	_, _, err = dao.List(ctx, Query{Size: 2, Sort: "score"}, nil)
+	assert.NoError(t, err)
+}
+
+func TestQuery_BySort(t *testing.T) {
+	var db = newFilterDB(t)
+
+	names, err := filterNames(t, db, (&Query{Sort: "status desc, Name ASC"}).BySort)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alan", "bob", "alice", "carol"}, names)
+
+	for _, sort := range []string{"status; DROP TABLE filter", "password", "id up", "(CASE WHEN 1=1 THEN id END)"} {
+		_, err = filterNames(t, db, (&Query{Sort: sort}).BySort)
+		assert.True(t, errors.Is(err, errors.InvalidRequest), sort)
+	}
+
+	// select/omit的字段同样需在白名单中
+	for _, fields := range [][]string{{"name", "(SELECT password FROM filter)"}, {"1=1; DROP TABLE filter"}} {
+		_, err = filterNames(t, db, (&Query{Select: fields}).BySelect)
+		assert.True(t, errors.Is(err, errors.InvalidRequest), fields)
+		_, err = filterNames(t, db, (&Query{Omit: fields}).BySelect)
+		assert.True(t, errors.Is(err, errors.InvalidRequest), fields)
+	}
+	var list []filterModel
+	assert.NoError(t, db.Scopes((&Query{Select: []string{"Name", "status"}, Omit: []string{"remark"}}).BySelect).Find(&list).Error)
+	assert.Len(t, list, 4)
+
+	var query = Query{After: "bad cursor"}
+	assert.Error(t, query.Valid(nil))
+}
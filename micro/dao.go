@@ -5,6 +5,7 @@ import (
 	"errors"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
+	"reflect"
 	"time"
 )
 
@@ -33,14 +34,24 @@ type Dao struct {
 	db      func(ctx context.Context) *gorm.DB
 	model   schema.Tabler
 	deleted bool
+	history bool
 }
 
 type Equal map[string]interface{}
 
 type Update map[string]interface{}
 
+// conn 数据库连接, ctx中有同一数据库的事务(Transaction)时加入该事务
+func (d Dao) conn(ctx context.Context) *gorm.DB {
+	var db = d.db(ctx)
+	if state := txFrom(ctx, db); state != nil {
+		return state.db.WithContext(ctx)
+	}
+	return db
+}
+
 func (d Dao) DB(ctx context.Context) *gorm.DB {
-	var db = d.db(ctx).Model(d.model)
+	var db = d.conn(ctx).Model(d.model)
 
 	if d.deleted {
 		return db
@@ -82,9 +93,26 @@ func (d Dao) Count(ctx context.Context, equal Equal) (count int64, err error) {
 	return
 }
 
+// List 分页查询, 设置after/before时按游标分页(需要游标时使用ListCursor), NoTotal时不统计总数
 func (d Dao) List(ctx context.Context, query Query, equal Equal, out interface{}) (total int64, err error) {
+	if total, err = d.list(ctx, query, equal, out); err != nil {
+		return
+	}
+
+	if query.Before != "" {
+		reverse(reflect.ValueOf(out).Elem())
+	}
+
+	return
+}
+
+func (d Dao) list(ctx context.Context, query Query, equal Equal, out interface{}) (total int64, err error) {
+	if query.NoTotal {
+		return 0, d.DB(ctx).Scopes(query.ByQuery, d.equal(equal)).Find(out).Error
+	}
+
 	if err = d.DB(ctx).Transaction(func(tx *gorm.DB) (err error) {
-		if err = tx.Scopes(query.ByWhere, query.BySort, query.ByCustom, d.equal(equal)).Count(&total).Error; err != nil {
+		if err = tx.Scopes(query.ByWhere, query.ByCustom, d.equal(equal)).Count(&total).Error; err != nil {
 			return
 		}
 
@@ -127,12 +155,77 @@ func (d Dao) Create(ctx context.Context, equal Equal, value interface{}) (err er
 			}
 		}
 
-		return tx.Create(value).Error
+		if err = tx.Create(value).Error; err != nil || !d.history {
+			return
+		}
+
+		return d.created(ctx, tx, HistoryCreate, value)
 	})
 }
 
+// 写入创建的变更历史
+func (d Dao) created(ctx context.Context, tx *gorm.DB, op string, value interface{}) (err error) {
+	s, err := d.schema(tx)
+	if err != nil {
+		return
+	}
+
+	after, err := d.rowsOf(ctx, s, reflect.ValueOf(value))
+	if err != nil {
+		return
+	}
+
+	return d.writeHistory(ctx, tx, op, nil, after)
+}
+
 func (d Dao) Update(ctx context.Context, equal Equal, update Update) (err error) {
-	return d.Equal(ctx, equal).Updates(map[string]interface{}(update)).Error
+	_, err = d.update(ctx, equal, update, HistoryUpdate)
+	return
+}
+
+// update 更新并返回影响行数, 开启变更历史时在同一事务中写入历史
+func (d Dao) update(ctx context.Context, equal Equal, update Update, op string) (rows int64, err error) {
+	if !d.history {
+		var db = d.Equal(ctx, equal).Updates(map[string]interface{}(update))
+		return db.RowsAffected, db.Error
+	}
+
+	err = d.DB(ctx).Transaction(func(tx *gorm.DB) (err error) {
+		s, err := d.schema(tx)
+		if err != nil {
+			return
+		}
+
+		var records = reflect.New(reflect.SliceOf(d.modelType()))
+		if err = tx.Scopes(d.equal(equal)).Find(records.Interface()).Error; err != nil {
+			return
+		}
+
+		before, err := d.rowsOf(ctx, s, records)
+		if err != nil || len(before) == 0 {
+			return
+		}
+
+		var ids = make([]string, 0, len(before))
+		for id := range before {
+			ids = append(ids, id)
+		}
+
+		var db = tx.Scopes(d.equal(equal)).Updates(map[string]interface{}(update))
+		if err = db.Error; err != nil {
+			return
+		}
+		rows = db.RowsAffected
+
+		after, err := d.snapshot(tx, s, ids)
+		if err != nil {
+			return
+		}
+
+		return d.writeHistory(ctx, tx, op, before, after)
+	})
+
+	return
 }
 
 func (d Dao) Delete(ctx context.Context, equal Equal, update Update) (err error) {
@@ -149,7 +242,8 @@ func (d Dao) Delete(ctx context.Context, equal Equal, update Update) (err error)
 		update["deleted_at"] = time.Now().Unix()
 	}
 
-	return d.Update(ctx, equal, update)
+	_, err = d.update(ctx, equal, update, HistoryDelete)
+	return
 }
 
 func NewDao(db func(ctx context.Context) *gorm.DB, model schema.Tabler) Dao {
@@ -166,3 +260,31 @@ func (e Equal) Where() map[string]interface{} {
 func (u Update) Updates() map[string]interface{} {
 	return u
 }
+
+// Merge 合并条件, 相同key以后者为准
+func (e Equal) Merge(equals ...Equal) Equal {
+	var merged = make(Equal, len(e))
+	for key, value := range e {
+		merged[key] = value
+	}
+	for _, equal := range equals {
+		for key, value := range equal {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// Merge 合并更新, 相同key以后者为准
+func (u Update) Merge(updates ...Update) Update {
+	var merged = make(Update, len(u))
+	for key, value := range u {
+		merged[key] = value
+	}
+	for _, update := range updates {
+		for key, value := range update {
+			merged[key] = value
+		}
+	}
+	return merged
+}
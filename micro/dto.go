@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zooyer/miskit/errors"
 	"gorm.io/gorm"
 )
 
@@ -16,10 +18,14 @@ type Query struct {
 	Page   int      `form:"page" json:"page,omitempty"`     // 当前页
 	Size   int      `form:"size" json:"size,omitempty"`     // 页大小
 	Sort   string   `form:"sort" json:"sort,omitempty"`     // 排序
-	Cond   string   `form:"cond" json:"cond,omitempty"`     // 条件符号：and、or，用于自定义字段查询
+	Cond   string   `form:"cond" json:"cond,omitempty"`     // 条件符号：and、or、not，用于自定义字段查询
 	Omit   []string `form:"omit" json:"omit,omitempty"`     // 忽略字段
 	Select []string `form:"select" json:"select,omitempty"` // 选择字段
-	Where  string   `form:"where" json:"where,omitempty"`   // 自定义条件SQL
+	Where  string   `form:"where" json:"where,omitempty"`   // 过滤条件, 语法见Filter
+
+	After   string `form:"after" json:"after,omitempty"`       // 游标分页, 取该游标之后的记录
+	Before  string `form:"before" json:"before,omitempty"`     // 游标分页, 取该游标之前的记录
+	NoTotal bool   `form:"no_total" json:"no_total,omitempty"` // 不统计总数
 }
 
 type Result struct {
@@ -43,43 +49,100 @@ func init() {
 	}
 }
 
+// BySelect 选择和忽略字段, 字段需在模型白名单中
 func (q *Query) BySelect(db *gorm.DB) *gorm.DB {
-	if len(q.Select) > 0 {
-		db = db.Select(q.Select)
+	if len(q.Select) == 0 && len(q.Omit) == 0 {
+		return db
+	}
+
+	s, err := modelSchema(db)
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+
+	var columns = Columns(s)
+	var mapping = func(fields []string) ([]string, error) {
+		var names = make([]string, len(fields))
+		for i, field := range fields {
+			name, exists := columns[field]
+			if !exists {
+				return nil, fmt.Errorf("select: unknown field %q", field)
+			}
+			names[i] = name
+		}
+		return names, nil
+	}
+
+	selected, err := mapping(q.Select)
+	if err != nil {
+		_ = db.AddError(errors.New(errors.InvalidRequest, err))
+		return db
+	}
+	omitted, err := mapping(q.Omit)
+	if err != nil {
+		_ = db.AddError(errors.New(errors.InvalidRequest, err))
+		return db
 	}
 
-	if len(q.Omit) > 0 {
-		db = db.Omit(q.Omit...)
+	if len(selected) > 0 {
+		db = db.Select(selected)
+	}
+	if len(omitted) > 0 {
+		db = db.Omit(omitted...)
 	}
 
 	return db
 }
 
 func (q *Query) ByLimit(db *gorm.DB) *gorm.DB {
-	if q.Page > 0 {
+	if q.Page > 0 && !q.cursor() {
 		db = db.Offset((q.Page - 1) * q.Size)
 	}
 
 	return db.Limit(q.Size)
 }
 
+// BySort 排序, 字段需在模型白名单中; 游标分页时追加主键, before时逆序
 func (q *Query) BySort(db *gorm.DB) *gorm.DB {
-	return db.Order(q.Sort)
+	if q.Sort == "" && !q.cursor() {
+		return db
+	}
+
+	s, err := modelSchema(db)
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+
+	var keys []sortKey
+	if q.cursor() {
+		keys, err = cursorKeys(q.Sort, s)
+	} else {
+		keys, err = parseSort(q.Sort, s)
+	}
+	if err != nil {
+		_ = db.AddError(errors.New(errors.InvalidRequest, err))
+		return db
+	}
+
+	return db.Clauses(orderBy(keys, q.Before != ""))
 }
 
+// ByWhere 按Where过滤条件查询, 语法见Filter
 func (q *Query) ByWhere(db *gorm.DB) *gorm.DB {
-	if q.Where != "" {
-		db = db.Where(q.Where)
+	filter, err := ParseFilter(q.Where)
+	if err != nil {
+		_ = db.AddError(errors.New(errors.InvalidRequest, err))
+		return db
 	}
-	return db
+
+	return filter.Scope(db)
 }
 
+// ByCustom 按自定义字段查询, 字段需在模型白名单中
 func (q *Query) ByCustom(db *gorm.DB) *gorm.DB {
-	var (
-		query   string
-		args    []interface{}
-		isFirst = true
-	)
+	var filters []Filter
 
 	for key, val := range q.form {
 		if omitParams[key] || len(val) == 0 || len(val) == 1 && len(val[0]) == 0 {
@@ -95,48 +158,48 @@ func (q *Query) ByCustom(db *gorm.DB) *gorm.DB {
 			}
 		}
 
-		// 查询条件
-		if isFirst {
-			isFirst = false
-		} else {
-			switch q.Cond {
-			case "or":
-				query += " OR "
-			case "and":
-				query += " AND "
-			case "not":
-				query += " NOT "
-			default:
-				query += " AND "
-			}
-		}
-
+		var filter = Filter{Field: key, Value: val[0]}
 		switch op {
 		case '^': // 前缀匹配
-			query += fmt.Sprintf("%s LIKE ?", key)
-			args = append(args, fmt.Sprintf("%s%%", val[0]))
+			filter.Op = OpPrefix
 		case '$': // 后缀匹配
-			query += fmt.Sprintf("%s LIKE ?", key)
-			args = append(args, fmt.Sprintf("%%%s", val[0]))
+			filter.Op = OpSuffix
 		case '*': // 模糊匹配
-			query += fmt.Sprintf("%s LIKE ?", key)
-			args = append(args, fmt.Sprintf("%%%s%%", val[0]))
+			filter.Op = OpLike
 		case '=': // 等值匹配
-			query += fmt.Sprintf("`%s` = ?", key)
-			args = append(args, val[0])
+			filter.Op = OpEq
 		case '\\':
 			fallthrough
 		default: // 数组匹配 (没有操作符或是数组全部是等值匹配)
-			query += fmt.Sprintf("%s IN (?)", key)
-			args = append(args, val)
+			filter.Op, filter.Value = OpIn, val
 		}
+		filters = append(filters, filter)
+	}
+
+	if len(filters) == 0 {
+		return db
+	}
+
+	// 按字段名排序, 保证生成的SQL稳定
+	sort.Slice(filters, func(i, j int) bool {
+		return filters[i].Field < filters[j].Field
+	})
+
+	var filter Filter
+	switch q.Cond {
+	case "or":
+		filter.Or = filters
+	case "not":
+		filter.Not = &Filter{Or: filters}
+	default:
+		filter.And = filters
 	}
 
-	return db.Where(query, args...)
+	return filter.Scope(db)
 }
 
 func (q *Query) ByQuery(db *gorm.DB) *gorm.DB {
-	return db.Scopes(q.BySelect, q.ByWhere, q.ByCustom, q.ByLimit, q.BySort)
+	return db.Scopes(q.BySelect, q.ByWhere, q.ByCustom, q.ByCursor, q.ByLimit, q.BySort)
 }
 
 func (q *Query) Valid(ctx *gin.Context) (err error) {
@@ -148,6 +211,22 @@ func (q *Query) Valid(ctx *gin.Context) (err error) {
 		q.Sort = "id DESC"
 	}
 
+	if _, err = ParseFilter(q.Where); err != nil {
+		return
+	}
+
+	if q.After != "" && q.Before != "" {
+		return fmt.Errorf("cursor: after and before are exclusive")
+	}
+	for _, cursor := range []string{q.After, q.Before} {
+		if cursor == "" {
+			continue
+		}
+		if _, err = decodeCursor(cursor, nil); err != nil {
+			return
+		}
+	}
+
 	if err = ctx.Request.ParseForm(); err != nil {
 		return
 	}
@@ -42,7 +42,7 @@ func TestQuery_Valid(t *testing.T) {
 
 	var resp *httptest.ResponseRecorder
 
-	resp = get("/valid?size=1&sort=id%20ASC&select=name&name=zhangsan&age=24&omit=age&page=1&where=phone%20%3D%20132")
+	resp = get("/valid?size=1&sort=id%20ASC&select=name&name=zhangsan&age=24&omit=age&page=1&where=phone:eq:132")
 	assert.Equal(t, http.StatusOK, resp.Code)
 	data, _ := ioutil.ReadAll(resp.Body)
 	t.Log(string(data))
@@ -61,9 +61,9 @@ func TestQuery_ByQuery(t *testing.T) {
 		Page:   2,
 		Size:   1,
 		Sort:   "id ASC",
-		Omit:   []string{"level", "field", "ab"},
+		Omit:   []string{"level", "field"},
 		Select: []string{"name", "age", "field"},
-		Where:  "level < 10",
+		Where:  "level:lt:10",
 	}
 
 	dial := sqlite.Open(":memory:")
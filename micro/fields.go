@@ -0,0 +1,214 @@
+package micro
+
+import (
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// AuditFields gorm插件, 按ctx中的操作人身份填充Model的created/updated/deleted字段, 创建时填充CountModel的统计字段
+//
+//	db.Use(&micro.AuditFields{})
+type AuditFields struct {
+	Resolver IdentityResolver // 身份解析器, 默认IdentityFrom
+	Now      func() time.Time // 当前时间, 默认time.Now
+}
+
+func (a *AuditFields) Name() string {
+	return "micro:audit_fields"
+}
+
+func (a *AuditFields) Initialize(db *gorm.DB) (err error) {
+	if err = db.Callback().Create().Before("gorm:create").Register(a.Name()+":create", a.create); err != nil {
+		return
+	}
+
+	if err = db.Callback().Update().Before("gorm:update").Register(a.Name()+":update", a.update); err != nil {
+		return
+	}
+
+	return
+}
+
+func (a *AuditFields) identity(db *gorm.DB) (Identity, bool) {
+	if a.Resolver != nil {
+		return a.Resolver(db.Statement.Context)
+	}
+	return IdentityFrom(db.Statement.Context)
+}
+
+func (a *AuditFields) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+// 创建时只填充零值字段, 不覆盖调用方显式设置的值
+func (a *AuditFields) create(db *gorm.DB) {
+	var stmt = db.Statement
+	if db.Error != nil || stmt.Schema == nil {
+		return
+	}
+
+	var (
+		now           = a.now()
+		identity, has = a.identity(db)
+		ctx           = stmt.Context
+		created       = stmt.Schema.LookUpField("created_at")
+	)
+
+	var set = func(row reflect.Value, name string, value interface{}) {
+		if field := stmt.Schema.LookUpField(name); field != nil {
+			if _, zero := field.ValueOf(ctx, row); zero {
+				_ = db.AddError(field.Set(ctx, row, value))
+			}
+		}
+	}
+
+	eachRow(stmt, func(row reflect.Value) {
+		var at = now
+		if created != nil {
+			if value, zero := created.ValueOf(ctx, row); zero {
+				_ = db.AddError(created.Set(ctx, row, timestamp(created, now)))
+			} else {
+				at = timeOf(created, value, now)
+			}
+		}
+
+		if has {
+			set(row, "created_id", identity.ID)
+			set(row, "created_by", identity.Name)
+			set(row, "updated_id", identity.ID)
+			set(row, "updated_by", identity.Name)
+		}
+
+		_, week := at.ISOWeek()
+		set(row, "count_by_year", at.Year())
+		set(row, "count_by_month", int(at.Month()))
+		set(row, "count_by_week", week)
+		set(row, "count_by_day", at.Day())
+		set(row, "count_by_hour", at.Hour())
+	})
+}
+
+// 更新时填充updated字段, 更新deleted_at(软删除)时填充deleted字段, 调用方已指定的字段不覆盖
+func (a *AuditFields) update(db *gorm.DB) {
+	var stmt = db.Statement
+	if db.Error != nil || stmt.Schema == nil {
+		return
+	}
+
+	identity, has := a.identity(db)
+	if !has {
+		return
+	}
+
+	var (
+		values, isMap = mapOf(stmt.Dest)
+		deleted       bool
+		changed       bool
+	)
+	if isMap {
+		// deleted_at为nil或零值时为恢复软删除
+		if value, exists := values["deleted_at"]; exists && value != nil {
+			var rv = reflect.ValueOf(value)
+			deleted = rv.IsValid() && !rv.IsZero()
+		}
+	}
+
+	var set = func(name string, value interface{}) {
+		if stmt.Schema.LookUpField(name) == nil {
+			return
+		}
+		if !isMap {
+			stmt.SetColumn(name, value, true)
+			return
+		}
+		if _, exists := values[name]; !exists {
+			values[name], changed = value, true
+		}
+	}
+
+	set("updated_id", identity.ID)
+	set("updated_by", identity.Name)
+	if deleted {
+		set("deleted_id", identity.ID)
+		set("deleted_by", identity.Name)
+	}
+
+	// 写入副本, 不修改调用方的map(调用方可能复用)
+	if changed {
+		stmt.Dest = values
+	}
+}
+
+// 更新的map(map[string]interface{}、Update等任意string键的map)转为副本, 非map返回false
+func mapOf(dest interface{}) (map[string]interface{}, bool) {
+	var rv = reflect.ValueOf(dest)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	var values = make(map[string]interface{}, rv.Len()+4)
+	for iter := rv.MapRange(); iter.Next(); {
+		values[iter.Key().String()] = iter.Value().Interface()
+	}
+	return values, true
+}
+
+func eachRow(stmt *gorm.Statement, fn func(row reflect.Value)) {
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			if row := reflect.Indirect(stmt.ReflectValue.Index(i)); row.Kind() == reflect.Struct {
+				fn(row)
+			}
+		}
+	case reflect.Struct:
+		if stmt.ReflectValue.CanAddr() {
+			fn(stmt.ReflectValue)
+		}
+	}
+}
+
+// 按字段类型和autoCreateTime精度生成时间值
+func timestamp(field *schema.Field, now time.Time) interface{} {
+	if field.FieldType.Kind() == reflect.Struct || field.FieldType.Kind() == reflect.Ptr {
+		return now
+	}
+
+	switch field.AutoCreateTime {
+	case schema.UnixMillisecond:
+		return now.UnixNano() / int64(time.Millisecond)
+	case schema.UnixNanosecond:
+		return now.UnixNano()
+	}
+	return now.Unix()
+}
+
+func timeOf(field *schema.Field, value interface{}, def time.Time) time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case *time.Time:
+		if v != nil {
+			return *v
+		}
+	case int64, int, int32, uint, uint32, uint64:
+		var n = reflect.ValueOf(v).Convert(reflect.TypeOf(int64(0))).Int()
+		switch field.AutoCreateTime {
+		case schema.UnixMillisecond:
+			return time.Unix(0, n*int64(time.Millisecond))
+		case schema.UnixNanosecond:
+			return time.Unix(0, n)
+		}
+		return time.Unix(n, 0)
+	}
+	return def
+}
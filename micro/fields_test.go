@@ -0,0 +1,133 @@
+package micro
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type fieldsModel struct {
+	Model
+	CountModel
+	Name string `json:"name"`
+}
+
+func (fieldsModel) TableName() string {
+	return "fields"
+}
+
+func TestAuditFields(t *testing.T) {
+	db, err := OpenDB("sqlite", ":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var now = time.Date(2024, 3, 5, 14, 30, 0, 0, time.Local)
+	if err = db.Use(&AuditFields{Now: func() time.Time { return now }}); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(new(fieldsModel)); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ctx = WithIdentity(context.Background(), Identity{ID: "1", Name: "alice"})
+		dao = NewTypedDao[fieldsModel](func(ctx context.Context) *gorm.DB {
+			return db.WithContext(ctx)
+		})
+	)
+
+	var m = fieldsModel{Name: "a"}
+	assert.NoError(t, dao.Create(ctx, &m))
+	assert.Equal(t, now.Unix(), m.CreatedAt)
+	assert.Equal(t, "1", m.CreatedID)
+	assert.Equal(t, "alice", m.CreatedBy)
+	assert.Equal(t, "alice", m.UpdatedBy)
+	assert.Equal(t, CountModel{CountByYear: 2024, CountByMonth: 3, CountByWeek: 10, CountByDay: 5, CountByHour: 14}, m.CountModel)
+
+	// 显式设置的值不覆盖, 统计字段按created_at计算
+	var created = time.Date(2023, 12, 31, 23, 0, 0, 0, time.Local)
+	var batch = []fieldsModel{
+		{Name: "b", Model: Model{CreatedAt: created.Unix(), CreatedBy: "system"}},
+		{Name: "c"},
+	}
+	assert.NoError(t, dao.BatchCreate(ctx, batch, 0))
+	assert.Equal(t, "system", batch[0].CreatedBy)
+	assert.Equal(t, "1", batch[0].CreatedID)
+	assert.Equal(t, CountModel{CountByYear: 2023, CountByMonth: 12, CountByWeek: 52, CountByDay: 31, CountByHour: 23}, batch[0].CountModel)
+	assert.Equal(t, "alice", batch[1].CreatedBy)
+
+	// 更新和软删除
+	ctx = WithIdentity(context.Background(), Identity{ID: "2", Name: "bob"})
+	var update = Update{"name": "a2"}
+	assert.NoError(t, dao.Update(ctx, Equal{"name": "a"}, update))
+	assert.Equal(t, Update{"name": "a2"}, update) // 不修改调用方的map
+	assert.NoError(t, dao.Update(ctx, Equal{"name": "c"}, Update{"name": "c2", "updated_by": "admin"}))
+	assert.NoError(t, dao.Delete(ctx, Equal{"name": "b"}, Update{}))
+
+	list, err := dao.QueryDeleted(true).Find(ctx, nil)
+	assert.NoError(t, err)
+	assert.Len(t, list, 3)
+
+	assert.Equal(t, "a2", list[0].Name)
+	assert.Equal(t, "alice", list[0].CreatedBy)
+	assert.Equal(t, "bob", list[0].UpdatedBy)
+	assert.Equal(t, "2", list[0].UpdatedID)
+	assert.Empty(t, list[0].DeletedBy)
+
+	assert.NotZero(t, list[1].DeletedAt)
+	assert.Equal(t, "bob", list[1].DeletedBy)
+	assert.Equal(t, "2", list[1].DeletedID)
+
+	assert.Equal(t, "admin", list[2].UpdatedBy)
+	assert.Equal(t, "2", list[2].UpdatedID)
+
+	// 恢复软删除(deleted_at为nil), 不填充deleted字段
+	assert.NotPanics(t, func() {
+		assert.NoError(t, dao.QueryDeleted(true).Update(ctx, Equal{"name": "b"}, Update{"deleted_at": nil}))
+	})
+
+	// 直接使用gorm更新Update类型
+	assert.NoError(t, db.WithContext(WithIdentity(ctx, Identity{ID: "3", Name: "carol"})).
+		Model(new(fieldsModel)).Where("name = ?", "a2").Updates(Update{"name": "a3"}).Error)
+
+	list, err = dao.QueryDeleted(true).Find(ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "a3", list[0].Name)
+	assert.Equal(t, "carol", list[0].UpdatedBy)
+	assert.Equal(t, "3", list[0].UpdatedID)
+	assert.Zero(t, list[1].DeletedAt)
+	assert.Equal(t, "bob", list[1].DeletedBy)
+
+	// 无身份时只填充统计字段
+	var anonymous = fieldsModel{Name: "d"}
+	assert.NoError(t, dao.Create(context.Background(), &anonymous))
+	assert.Empty(t, anonymous.CreatedBy)
+	assert.Equal(t, int16(2024), anonymous.CountByYear)
+}
+
+type testUserKey struct{}
+
+func TestIdentityFrom(t *testing.T) {
+	var ctx = context.WithValue(context.Background(), identityKey{}, "invalid")
+	_, ok := IdentityFrom(ctx)
+	assert.False(t, ok)
+
+	RegisterIdentityResolver(func(ctx context.Context) (Identity, bool) {
+		if name, ok := ctx.Value(testUserKey{}).(string); ok {
+			return Identity{Name: name}, true
+		}
+		return Identity{}, false
+	})
+	defer func() { resolvers = nil }()
+
+	identity, ok := IdentityFrom(context.WithValue(ctx, testUserKey{}, "zs"))
+	assert.True(t, ok)
+	assert.Equal(t, "zs", identity.Name)
+
+	identity, _ = IdentityFrom(WithIdentity(context.WithValue(ctx, testUserKey{}, "zs"), Identity{Name: "ls"}))
+	assert.Equal(t, "ls", identity.Name)
+}
@@ -0,0 +1,260 @@
+package micro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/zooyer/miskit/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 过滤操作符
+const (
+	OpEq      = "eq"      // 等于
+	OpNe      = "ne"      // 不等于
+	OpGt      = "gt"      // 大于
+	OpGte     = "gte"     // 大于等于
+	OpLt      = "lt"      // 小于
+	OpLte     = "lte"     // 小于等于
+	OpIn      = "in"      // 在列表中, 紧凑语法以|分隔
+	OpNin     = "nin"     // 不在列表中
+	OpLike    = "like"    // 模糊匹配
+	OpPrefix  = "prefix"  // 前缀匹配
+	OpSuffix  = "suffix"  // 后缀匹配
+	OpNull    = "null"    // 为NULL
+	OpNotNull = "notnull" // 不为NULL
+)
+
+const (
+	maxFilterDepth      = 8  // 最大嵌套层数
+	maxFilterConditions = 64 // 最大条件数
+)
+
+// Filter 过滤条件树, And/Or/Not与Field条件互斥
+//
+// 紧凑语法: 多个条件以逗号分隔(AND), 条件格式为 字段:操作符:值, 值中的逗号以\,转义
+//
+//	status:eq:1,name:like:foo,created_at:gte:1700000000,type:in:a|b
+//
+// JSON语法:
+//
+//	{"or":[{"field":"status","op":"eq","value":1},{"not":{"field":"name","op":"prefix","value":"test"}}]}
+type Filter struct {
+	And   []Filter    `json:"and,omitempty"`
+	Or    []Filter    `json:"or,omitempty"`
+	Not   *Filter     `json:"not,omitempty"`
+	Field string      `json:"field,omitempty"`
+	Op    string      `json:"op,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// ParseFilter 解析过滤条件, 以{开头时按JSON语法解析, 否则按紧凑语法解析
+func ParseFilter(s string) (filter Filter, err error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return
+	}
+
+	if s[0] == '{' {
+		if err = json.Unmarshal([]byte(s), &filter); err != nil {
+			return filter, fmt.Errorf("filter: %w", err)
+		}
+		return
+	}
+
+	for _, cond := range splitEscaped(s, ',') {
+		var parts = strings.SplitN(cond, ":", 3)
+		switch {
+		case len(parts) == 2 && (parts[1] == OpNull || parts[1] == OpNotNull):
+		case len(parts) == 3:
+		default:
+			return filter, fmt.Errorf("filter: invalid condition %q", cond)
+		}
+
+		var f = Filter{Field: strings.TrimSpace(parts[0]), Op: parts[1]}
+		if len(parts) == 3 {
+			f.Value = parts[2]
+			if f.Op == OpIn || f.Op == OpNin {
+				f.Value = strings.Split(parts[2], "|")
+			}
+		}
+		filter.And = append(filter.And, f)
+	}
+
+	return
+}
+
+// 按sep分割, \转义sep和\本身
+func splitEscaped(s string, sep byte) (list []string) {
+	var (
+		buf    strings.Builder
+		escape bool
+	)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case escape:
+			buf.WriteByte(c)
+			escape = false
+		case c == '\\':
+			escape = true
+		case c == sep:
+			list = append(list, buf.String())
+			buf.Reset()
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return append(list, buf.String())
+}
+
+// Columns 模型的字段白名单, 字段名、数据库列名和json名均映射到数据库列名
+func Columns(s *schema.Schema) map[string]string {
+	var columns = make(map[string]string, len(s.Fields)*2)
+	for _, field := range s.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		columns[field.DBName] = field.DBName
+		columns[field.Name] = field.DBName
+		if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			columns[name] = field.DBName
+		}
+	}
+	return columns
+}
+
+// Empty 是否无任何条件
+func (f Filter) Empty() bool {
+	return len(f.And) == 0 && len(f.Or) == 0 && f.Not == nil && f.Field == ""
+}
+
+// Build 按字段白名单生成查询条件, 不在白名单中的字段和未知操作符返回错误
+func (f Filter) Build(columns map[string]string) (clause.Expression, error) {
+	var count int
+	return f.build(columns, 0, &count)
+}
+
+func (f Filter) build(columns map[string]string, depth int, count *int) (clause.Expression, error) {
+	if depth > maxFilterDepth {
+		return nil, fmt.Errorf("filter: nesting exceeds %d levels", maxFilterDepth)
+	}
+
+	var list = func(filters []Filter) ([]clause.Expression, error) {
+		var exprs = make([]clause.Expression, 0, len(filters))
+		for _, filter := range filters {
+			expr, err := filter.build(columns, depth+1, count)
+			if err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, expr)
+		}
+		return exprs, nil
+	}
+
+	switch {
+	case len(f.And) > 0:
+		exprs, err := list(f.And)
+		if err != nil {
+			return nil, err
+		}
+		return clause.And(exprs...), nil
+	case len(f.Or) > 0:
+		exprs, err := list(f.Or)
+		if err != nil {
+			return nil, err
+		}
+		return clause.Or(exprs...), nil
+	case f.Not != nil:
+		expr, err := f.Not.build(columns, depth+1, count)
+		if err != nil {
+			return nil, err
+		}
+		return clause.Not(expr), nil
+	}
+
+	if *count++; *count > maxFilterConditions {
+		return nil, fmt.Errorf("filter: conditions exceed %d", maxFilterConditions)
+	}
+
+	name, exists := columns[f.Field]
+	if !exists {
+		return nil, fmt.Errorf("filter: unknown field %q", f.Field)
+	}
+
+	var column = clause.Column{Name: name}
+
+	switch f.Op {
+	case OpEq:
+		return clause.Eq{Column: column, Value: f.Value}, nil
+	case OpNe:
+		return clause.Neq{Column: column, Value: f.Value}, nil
+	case OpGt:
+		return clause.Gt{Column: column, Value: f.Value}, nil
+	case OpGte:
+		return clause.Gte{Column: column, Value: f.Value}, nil
+	case OpLt:
+		return clause.Lt{Column: column, Value: f.Value}, nil
+	case OpLte:
+		return clause.Lte{Column: column, Value: f.Value}, nil
+	case OpIn, OpNin:
+		values, ok := f.Value.([]interface{})
+		if !ok {
+			if strs, isStrs := f.Value.([]string); isStrs {
+				values = make([]interface{}, len(strs))
+				for i, s := range strs {
+					values[i] = s
+				}
+			} else {
+				values = []interface{}{f.Value}
+			}
+		}
+		if f.Op == OpNin {
+			return clause.Not(clause.IN{Column: column, Values: values}), nil
+		}
+		return clause.IN{Column: column, Values: values}, nil
+	case OpLike:
+		return clause.Like{Column: column, Value: fmt.Sprintf("%%%v%%", f.Value)}, nil
+	case OpPrefix:
+		return clause.Like{Column: column, Value: fmt.Sprintf("%v%%", f.Value)}, nil
+	case OpSuffix:
+		return clause.Like{Column: column, Value: fmt.Sprintf("%%%v", f.Value)}, nil
+	case OpNull:
+		return clause.Eq{Column: column, Value: nil}, nil
+	case OpNotNull:
+		return clause.Neq{Column: column, Value: nil}, nil
+	}
+
+	return nil, fmt.Errorf("filter: unknown operator %q", f.Op)
+}
+
+// Scope 生成gorm查询条件, 字段白名单取自db的模型(未设置模型时取Find的目标), 错误以InvalidRequest加入db
+func (f Filter) Scope(db *gorm.DB) *gorm.DB {
+	if f.Empty() {
+		return db
+	}
+
+	columns, err := modelColumns(db)
+	if err != nil {
+		_ = db.AddError(err)
+		return db
+	}
+
+	expr, err := f.Build(columns)
+	if err != nil {
+		_ = db.AddError(errors.New(errors.InvalidRequest, err))
+		return db
+	}
+
+	return db.Where(expr)
+}
+
+func modelColumns(db *gorm.DB) (map[string]string, error) {
+	s, err := modelSchema(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return Columns(s), nil
+}
@@ -0,0 +1,153 @@
+package micro
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zooyer/miskit/errors"
+	"gorm.io/gorm"
+)
+
+type filterModel struct {
+	Model
+	Name   string `json:"name"`
+	Status int    `json:"status"`
+	Remark string `json:"remark"`
+}
+
+func (filterModel) TableName() string {
+	return "filter"
+}
+
+func newFilterDB(t *testing.T) *gorm.DB {
+	db, err := OpenDB("sqlite", ":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.AutoMigrate(new(filterModel)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []filterModel{
+		{Name: "alice", Status: 1, Remark: "a,b"},
+		{Name: "bob", Status: 2},
+		{Name: "carol", Status: 1},
+		{Name: "alan", Status: 3},
+	} {
+		if err = db.Create(&m).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return db.Model(new(filterModel)).Session(&gorm.Session{})
+}
+
+func filterNames(t *testing.T, db *gorm.DB, scopes ...func(*gorm.DB) *gorm.DB) (names []string, err error) {
+	// scopes在执行时才调用, 以scope追加id排序保证在scopes的排序之后
+	err = db.Scopes(append(scopes, func(db *gorm.DB) *gorm.DB { return db.Order("id") })...).Pluck("name", &names).Error
+	return
+}
+
+func TestParseFilter(t *testing.T) {
+	filter, err := ParseFilter(`status:in:1|2, name:prefix:a,remark:eq:a\,b,deleted_by:null,created_at:gte:2023-01-01 00:00:00`)
+	assert.NoError(t, err)
+	assert.Equal(t, Filter{And: []Filter{
+		{Field: "status", Op: OpIn, Value: []string{"1", "2"}},
+		{Field: "name", Op: OpPrefix, Value: "a"},
+		{Field: "remark", Op: OpEq, Value: "a,b"},
+		{Field: "deleted_by", Op: OpNull},
+		{Field: "created_at", Op: OpGte, Value: "2023-01-01 00:00:00"},
+	}}, filter)
+
+	filter, err = ParseFilter(`{"or":[{"field":"status","op":"eq","value":1},{"not":{"field":"name","op":"like","value":"o"}}]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, Filter{Or: []Filter{
+		{Field: "status", Op: OpEq, Value: float64(1)},
+		{Not: &Filter{Field: "name", Op: OpLike, Value: "o"}},
+	}}, filter)
+
+	filter, err = ParseFilter("")
+	assert.NoError(t, err)
+	assert.True(t, filter.Empty())
+
+	for _, s := range []string{"status", "status:eq", "{bad"} {
+		_, err = ParseFilter(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFilter_Scope(t *testing.T) {
+	var db = newFilterDB(t)
+
+	var tests = map[string][]string{
+		"status:eq:1":              {"alice", "carol"},
+		"status:in:2|3":            {"bob", "alan"},
+		"status:nin:2|3":           {"alice", "carol"},
+		"name:prefix:al":           {"alice", "alan"},
+		"name:suffix:n":            {"alan"},
+		"name:like:o,status:gte:2": {"bob"},
+		"remark:eq:a\\,b":          {"alice"},
+		"Status:ne:1,status:lt:3":  {"bob"},
+		`{"or":[{"field":"name","op":"eq","value":"bob"},{"not":{"field":"status","op":"lte","value":2}}]}`: {"bob", "alan"},
+	}
+
+	for where, expect := range tests {
+		var query = Query{Where: where}
+		names, err := filterNames(t, db, query.ByWhere)
+		assert.NoError(t, err, where)
+		assert.Equal(t, expect, names, where)
+	}
+}
+
+func TestFilter_Reject(t *testing.T) {
+	var db = newFilterDB(t)
+
+	for _, where := range []string{
+		"password:eq:1",
+		"name = 'bob' OR 1=1--:eq:1",
+		"name:regexp:.*",
+		`{"field":"1=1) OR (1","op":"eq","value":1}`,
+	} {
+		var query = Query{Where: where}
+		_, err := filterNames(t, db, query.ByWhere)
+		assert.True(t, errors.Is(err, errors.InvalidRequest), where)
+	}
+
+	// 嵌套层数限制
+	var filter = Filter{Field: "status", Op: OpEq, Value: 1}
+	for i := 0; i <= maxFilterDepth; i++ {
+		filter = Filter{Not: &filter}
+	}
+	_, err := filter.Build(map[string]string{"status": "status"})
+	assert.Error(t, err)
+}
+
+func TestQuery_ByCustom(t *testing.T) {
+	var db = newFilterDB(t)
+
+	var tests = []struct {
+		cond   string
+		form   url.Values
+		expect []string
+	}{
+		{"", url.Values{"name": {"^al"}}, []string{"alice", "alan"}},
+		{"", url.Values{"name": {"$ce"}}, []string{"alice"}},
+		{"", url.Values{"name": {"*o"}, "status": {"=2"}}, []string{"bob"}},
+		{"", url.Values{"status": {"1", "3"}}, []string{"alice", "carol", "alan"}},
+		{"or", url.Values{"name": {"=bob"}, "status": {"3"}}, []string{"bob", "alan"}},
+		{"not", url.Values{"name": {"=bob"}, "status": {"3"}}, []string{"alice", "carol"}},
+	}
+
+	for _, test := range tests {
+		var query = Query{Cond: test.cond, form: test.form}
+		names, err := filterNames(t, db, query.ByCustom)
+		assert.NoError(t, err, test.form)
+		assert.Equal(t, test.expect, names, test.form)
+	}
+
+	var query = Query{form: url.Values{"name LIKE '%' OR 1=1 --": {"*x"}}}
+	_, err := filterNames(t, db, query.ByCustom)
+	assert.True(t, errors.Is(err, errors.InvalidRequest))
+}
@@ -0,0 +1,228 @@
+package micro
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// TypedDao 泛型Dao, 与Dao共用软删除语义, 查询结果直接返回T
+type TypedDao[T schema.Tabler] struct {
+	dao Dao
+}
+
+// Column 模型T的列, 由Col创建, 列名已校验
+type Column[T schema.Tabler] struct {
+	name string
+}
+
+// NewTypedDao 创建泛型Dao
+func NewTypedDao[T schema.Tabler](db func(ctx context.Context) *gorm.DB) TypedDao[T] {
+	var model T
+	return TypedDao[T]{dao: NewDao(db, model)}
+}
+
+// Dao 非泛型Dao, 用于Query等未提供泛型版本的方法
+func (d TypedDao[T]) Dao() Dao {
+	return d.dao
+}
+
+func (d TypedDao[T]) DB(ctx context.Context) *gorm.DB {
+	return d.dao.DB(ctx)
+}
+
+func (d TypedDao[T]) QueryDeleted(ok bool) TypedDao[T] {
+	return TypedDao[T]{dao: d.dao.QueryDeleted(ok)}
+}
+
+// WithHistory 开启变更历史, 同Dao.WithHistory
+func (d TypedDao[T]) WithHistory(ok bool) TypedDao[T] {
+	return TypedDao[T]{dao: d.dao.WithHistory(ok)}
+}
+
+// Histories 记录的变更历史
+func (d TypedDao[T]) Histories(ctx context.Context, id interface{}) ([]History, error) {
+	return d.dao.Histories(ctx, id)
+}
+
+// Restore 恢复到某条历史的版本
+func (d TypedDao[T]) Restore(ctx context.Context, historyID uint) error {
+	return d.dao.Restore(ctx, historyID)
+}
+
+// Get 按主键查询, 不存在时返回gorm.ErrRecordNotFound
+func (d TypedDao[T]) Get(ctx context.Context, id interface{}) (*T, error) {
+	var out T
+	if err := d.DB(ctx).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).First(&out).Error; err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (d TypedDao[T]) First(ctx context.Context, equal Equal) (*T, error) {
+	var out T
+	if err := d.dao.First(ctx, equal, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (d TypedDao[T]) Find(ctx context.Context, equal Equal) ([]T, error) {
+	var out []T
+	if err := d.dao.Find(ctx, equal, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (d TypedDao[T]) Count(ctx context.Context, equal Equal) (int64, error) {
+	return d.dao.Count(ctx, equal)
+}
+
+// List 分页查询, 同Dao.List
+func (d TypedDao[T]) List(ctx context.Context, query Query, equal Equal) ([]T, int64, error) {
+	var out []T
+	total, err := d.dao.List(ctx, query, equal, &out)
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+// ListCursor 游标分页查询, 同Dao.ListCursor
+func (d TypedDao[T]) ListCursor(ctx context.Context, query Query, equal Equal) ([]T, Cursor, error) {
+	var out []T
+	cursor, err := d.dao.ListCursor(ctx, query, equal, &out)
+	if err != nil {
+		return nil, Cursor{}, err
+	}
+	return out, cursor, nil
+}
+
+// Create 创建记录
+func (d TypedDao[T]) Create(ctx context.Context, value *T) error {
+	return d.create(ctx, HistoryCreate, func(tx *gorm.DB) *gorm.DB {
+		return tx.Create(value)
+	}, value)
+}
+
+// 创建并在开启变更历史时写入历史
+func (d TypedDao[T]) create(ctx context.Context, op string, create func(tx *gorm.DB) *gorm.DB, value interface{}) error {
+	if !d.dao.history {
+		return create(d.dao.conn(ctx)).Error
+	}
+
+	return d.dao.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := create(tx).Error; err != nil {
+			return err
+		}
+		return d.dao.created(ctx, tx, op, value)
+	})
+}
+
+// Upsert 创建记录, 主键或唯一键冲突时更新columns, 未指定columns时更新除主键、created_*和deleted_*外的全部列,
+// 冲突的记录已软删除时保持删除状态
+func (d TypedDao[T]) Upsert(ctx context.Context, value *T, columns ...Column[T]) error {
+	// 冲突时变更前的记录未知, 历史中按创建记录
+	return d.create(ctx, HistoryCreate, func(tx *gorm.DB) *gorm.DB {
+		var names = make([]string, len(columns))
+		for i, column := range columns {
+			names[i] = column.name
+		}
+
+		if len(names) == 0 {
+			var stmt = &gorm.Statement{DB: tx}
+			if err := stmt.Parse(value); err != nil {
+				_ = tx.AddError(err)
+				return tx
+			}
+			for _, name := range stmt.Schema.DBNames {
+				if field := stmt.Schema.FieldsByDBName[name]; field.PrimaryKey ||
+					strings.HasPrefix(name, "created_") || strings.HasPrefix(name, "deleted_") {
+					continue
+				}
+				names = append(names, name)
+			}
+		}
+
+		return tx.Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns(names)}).Create(value)
+	}, value)
+}
+
+// BatchCreate 批量创建, 每批size条, size<=0时一次创建
+func (d TypedDao[T]) BatchCreate(ctx context.Context, values []T, size int) error {
+	if len(values) == 0 {
+		return nil
+	}
+	if size <= 0 {
+		size = len(values)
+	}
+	return d.create(ctx, HistoryCreate, func(tx *gorm.DB) *gorm.DB {
+		return tx.CreateInBatches(values, size)
+	}, values)
+}
+
+func (d TypedDao[T]) Update(ctx context.Context, equal Equal, update Update) error {
+	return d.dao.Update(ctx, equal, update)
+}
+
+// UpdateWithVersion 按版本号更新, 同Dao.UpdateWithVersion
+func (d TypedDao[T]) UpdateWithVersion(ctx context.Context, equal Equal, version int64, update Update) error {
+	return d.dao.UpdateWithVersion(ctx, equal, version, update)
+}
+
+// Delete 软删除, 同Dao.Delete
+func (d TypedDao[T]) Delete(ctx context.Context, equal Equal, update Update) error {
+	return d.dao.Delete(ctx, equal, update)
+}
+
+// Col 模型T的列, name可以是字段名、列名或json名, 列名按db的NamingStrategy解析, 不存在时返回错误
+//
+//	userName, err := micro.Col[User](db, "name")
+//	dao.Find(ctx, userName.Eq("zs"))
+func Col[T schema.Tabler](db *gorm.DB, name string) (Column[T], error) {
+	var (
+		model T
+		stmt  = &gorm.Statement{DB: db}
+	)
+
+	if err := stmt.Parse(&model); err != nil {
+		return Column[T]{}, fmt.Errorf("micro: parse %T: %w", model, err)
+	}
+
+	column, exists := Columns(stmt.Schema)[name]
+	if !exists {
+		return Column[T]{}, fmt.Errorf("micro: %T has no column %q", model, name)
+	}
+
+	return Column[T]{name: column}, nil
+}
+
+// Col 模型T的列, 使用Dao的db解析, 同Col
+func (d TypedDao[T]) Col(name string) (Column[T], error) {
+	return Col[T](d.DB(context.Background()), name)
+}
+
+// Name 数据库列名
+func (c Column[T]) Name() string {
+	return c.name
+}
+
+// Eq 等值条件, 多个值时为IN条件
+func (c Column[T]) Eq(value interface{}) Equal {
+	return Equal{c.name: value}
+}
+
+// Set 更新字段
+func (c Column[T]) Set(value interface{}) Update {
+	return Update{c.name: value}
+}
+
+// Filter 过滤条件
+func (c Column[T]) Filter(op string, value interface{}) Filter {
+	return Filter{Field: c.name, Op: op, Value: value}
+}
@@ -0,0 +1,115 @@
+package micro
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func TestTypedDao(t *testing.T) {
+	var ctx = context.Background()
+
+	db, err := OpenDB("sqlite", ":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.AutoMigrate(new(testModel)); err != nil {
+		t.Fatal(err)
+	}
+
+	var dao = NewTypedDao[testModel](func(ctx context.Context) *gorm.DB {
+		return db.WithContext(ctx)
+	})
+
+	name, err := dao.Col("Name")
+	assert.NoError(t, err)
+	age, err := Col[testModel](db, "age")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "name", name.Name())
+	_, err = dao.Col("password")
+	assert.Error(t, err)
+
+	// 按db的NamingStrategy解析列名
+	custom, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{NameReplacer: strings.NewReplacer("Age", "Years")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	years, err := Col[testModel](custom, "age")
+	assert.NoError(t, err)
+	assert.Equal(t, "years", years.Name())
+
+	var dog = testModel{Name: "dog", Age: 3}
+	assert.NoError(t, dao.Create(ctx, &dog))
+	assert.NotZero(t, dog.ID)
+
+	assert.NoError(t, dao.BatchCreate(ctx, []testModel{{Name: "cat", Age: 2}, {Name: "fish", Age: 1}, {Name: "bird", Age: 1}}, 2))
+
+	got, err := dao.Get(ctx, dog.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "dog", got.Name)
+
+	list, total, err := dao.List(ctx, Query{Size: 2, Sort: "age, id"}, age.Eq([]int{1, 2}))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Len(t, list, 2)
+	assert.Equal(t, "fish", list[0].Name)
+
+	// 主键冲突时更新指定字段
+	dog.Name, dog.Age = "wolf", 5
+	assert.NoError(t, dao.Upsert(ctx, &dog, age))
+	got, err = dao.Get(ctx, dog.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "dog", got.Name)
+	assert.Equal(t, 5, got.Age)
+
+	assert.NoError(t, dao.Upsert(ctx, &dog))
+	got, err = dao.Get(ctx, dog.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "wolf", got.Name)
+
+	// 冲突的记录已软删除时保持删除状态, 创建信息不变
+	var fish = testModel{Name: "fish2", Age: 1}
+	assert.NoError(t, dao.Create(ctx, &fish))
+	assert.NoError(t, dao.Delete(ctx, name.Eq("fish2"), Update{}))
+	deleted, err := dao.QueryDeleted(true).Get(ctx, fish.ID)
+	assert.NoError(t, err)
+
+	fish.DeletedAt, fish.CreatedAt, fish.Age = 0, 1, 9
+	assert.NoError(t, dao.Upsert(ctx, &fish))
+	_, err = dao.Get(ctx, fish.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	got, err = dao.QueryDeleted(true).Get(ctx, fish.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 9, got.Age)
+	assert.Equal(t, deleted.DeletedAt, got.DeletedAt)
+	assert.Equal(t, deleted.CreatedAt, got.CreatedAt)
+
+	// 软删除后Get/Find不可见, QueryDeleted可见
+	assert.NoError(t, dao.Delete(ctx, name.Eq("wolf"), Update{}))
+	_, err = dao.Get(ctx, dog.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	found, err := dao.QueryDeleted(true).First(ctx, name.Eq("wolf"))
+	assert.NoError(t, err)
+	assert.NotZero(t, found.DeletedAt)
+
+	assert.NoError(t, dao.Update(ctx, name.Eq("cat"), age.Set(4).Merge(name.Set("tiger"))))
+	all, err := dao.Find(ctx, nil)
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+	assert.Equal(t, "tiger", all[0].Name)
+	assert.Equal(t, 4, all[0].Age)
+
+	count, err := dao.Count(ctx, age.Eq(1).Merge(Equal{"name": "bird"}))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
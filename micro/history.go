@@ -0,0 +1,300 @@
+package micro
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/zooyer/miskit/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 变更操作
+const (
+	HistoryCreate  = "create"
+	HistoryUpdate  = "update"
+	HistoryDelete  = "delete"
+	HistoryRestore = "restore"
+)
+
+// History 变更历史, 只追加不修改, 与变更在同一事务中写入;
+// 快照按列记录(含json:"-"字段), 敏感字段通过标签 history:"-" 排除, 不记录也不恢复
+//
+//	db.AutoMigrate(new(micro.History))
+//	dao := micro.NewDao(getDB, new(User)).WithHistory(true)
+type History struct {
+	ID        uint   `json:"id" gorm:"primary_key"`
+	Table     string `json:"table" gorm:"column:table_name;size:64;index:idx_history_record"`
+	RecordID  string `json:"record_id" gorm:"size:64;index:idx_history_record"`
+	Operation string `json:"operation" gorm:"size:16"`
+	Before    string `json:"before,omitempty"` // 变更前的记录(JSON, 列名 => 值), 创建时为空
+	After     string `json:"after,omitempty"`  // 变更后的记录(JSON, 列名 => 值)
+	Diff      string `json:"diff,omitempty"`   // 变更的列 {"列名":[变更前,变更后]}
+	ActorID   string `json:"actor_id" gorm:"index"`
+	Actor     string `json:"actor"`
+	TraceID   string `json:"trace_id" gorm:"index"`
+	CreatedAt int64  `json:"created_at" gorm:"index"`
+}
+
+func (History) TableName() string {
+	return "history"
+}
+
+// WithHistory 开启变更历史, Create/Update/Delete在同一事务中写入History
+func (d Dao) WithHistory(ok bool) Dao {
+	var dao = d
+	dao.history = ok
+
+	return dao
+}
+
+// Histories 记录的变更历史, 按时间正序
+func (d Dao) Histories(ctx context.Context, id interface{}) (histories []History, err error) {
+	var table = d.model.TableName()
+	if err = d.conn(ctx).Where("table_name = ? AND record_id = ?", table, fmt.Sprint(id)).Order("id").Find(&histories).Error; err != nil {
+		return
+	}
+	return
+}
+
+// Restore 将记录恢复为某条历史的变更后版本(包括软删除状态), 恢复操作同样写入历史
+func (d Dao) Restore(ctx context.Context, historyID uint) (err error) {
+	return d.conn(ctx).Transaction(func(tx *gorm.DB) (err error) {
+		var history History
+		if err = tx.First(&history, historyID).Error; err != nil {
+			return
+		}
+
+		if history.Table != d.model.TableName() {
+			return fmt.Errorf("history %d belongs to table %s", historyID, history.Table)
+		}
+
+		s, err := d.schema(tx)
+		if err != nil {
+			return
+		}
+
+		values, err := restoreValues(s, history.After)
+		if err != nil {
+			return
+		}
+
+		// 版本号不回退, 恢复视为一次新的修改, 避免过期版本号再次更新成功(ABA)
+		if s.LookUpField(versionColumn) != nil {
+			values[versionColumn] = gorm.Expr(versionColumn + " + 1")
+		}
+
+		var primary = s.PrioritizedPrimaryField
+		before, err := d.snapshot(tx, s, []string{history.RecordID})
+		if err != nil {
+			return
+		}
+
+		if len(values) > 0 {
+			if err = tx.Model(d.model).Where(clause.Eq{Column: clause.Column{Name: primary.DBName}, Value: history.RecordID}).
+				Updates(values).Error; err != nil {
+				return
+			}
+		}
+
+		after, err := d.snapshot(tx, s, []string{history.RecordID})
+		if err != nil {
+			return
+		}
+
+		return d.writeHistory(ctx, tx, HistoryRestore, before, after)
+	})
+}
+
+// 快照中存在的列 => 列值(按字段类型还原), 主键、created_*和updated_*列不恢复
+func restoreValues(s *schema.Schema, snapshot string) (map[string]interface{}, error) {
+	var columns map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(snapshot), &columns); err != nil {
+		return nil, err
+	}
+
+	var values = make(map[string]interface{}, len(columns))
+	for column, raw := range columns {
+		var field = s.LookUpField(column)
+		if field == nil || field.DBName != column || !historyField(field) || field.PrimaryKey ||
+			strings.HasPrefix(column, "created_") || strings.HasPrefix(column, "updated_") {
+			continue
+		}
+
+		var value = reflect.New(field.FieldType)
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			return nil, fmt.Errorf("history: restore %s: %w", column, err)
+		}
+		values[column] = value.Elem().Interface()
+	}
+
+	return values, nil
+}
+
+// 字段是否记录到历史, 敏感字段(如密码哈希、令牌)通过标签 history:"-" 排除
+func historyField(field *schema.Field) bool {
+	return field.DBName != "" && field.Tag.Get("history") != "-"
+}
+
+func (d Dao) modelType() reflect.Type {
+	var typ = reflect.TypeOf(d.model)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+func (d Dao) schema(tx *gorm.DB) (*schema.Schema, error) {
+	var stmt = &gorm.Statement{DB: tx}
+	if err := stmt.Parse(d.model); err != nil {
+		return nil, err
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("history: %s has no primary key", stmt.Schema.Table)
+	}
+	return stmt.Schema, nil
+}
+
+// 按主键查询记录快照(含软删除), key为主键字符串
+func (d Dao) snapshot(tx *gorm.DB, s *schema.Schema, ids []string) (map[string]map[string]interface{}, error) {
+	var rows = reflect.New(reflect.SliceOf(d.modelType()))
+	if len(ids) > 0 {
+		if err := tx.Session(&gorm.Session{NewDB: true}).Model(d.model).
+			Where(clause.IN{Column: clause.Column{Name: s.PrioritizedPrimaryField.DBName}, Values: toInterfaces(ids)}).
+			Find(rows.Interface()).Error; err != nil {
+			return nil, err
+		}
+	}
+	return d.rowsOf(tx.Statement.Context, s, rows.Elem())
+}
+
+// 记录转为 主键 => {列名: 值} 的快照
+func (d Dao) rowsOf(ctx context.Context, s *schema.Schema, rows reflect.Value) (map[string]map[string]interface{}, error) {
+	var snapshots = make(map[string]map[string]interface{})
+
+	var add = func(row reflect.Value) error {
+		row = reflect.Indirect(row)
+		if row.Kind() != reflect.Struct {
+			return nil
+		}
+
+		var columns = make(map[string]interface{}, len(s.Fields))
+		for _, field := range s.Fields {
+			if historyField(field) {
+				columns[field.DBName], _ = field.ValueOf(ctx, row)
+			}
+		}
+
+		// 经JSON转换, 与存储的快照比较时类型一致
+		data, err := json.Marshal(columns)
+		if err != nil {
+			return err
+		}
+
+		var snapshot map[string]interface{}
+		if err = json.Unmarshal(data, &snapshot); err != nil {
+			return err
+		}
+
+		id, _ := s.PrioritizedPrimaryField.ValueOf(ctx, row)
+		snapshots[fmt.Sprint(id)] = snapshot
+		return nil
+	}
+
+	rows = reflect.Indirect(rows)
+	switch rows.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rows.Len(); i++ {
+			if err := add(rows.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		if err := add(rows); err != nil {
+			return nil, err
+		}
+	}
+
+	return snapshots, nil
+}
+
+// 写入变更历史, 创建时before为空, 无变化的记录不写入
+func (d Dao) writeHistory(ctx context.Context, tx *gorm.DB, op string, before, after map[string]map[string]interface{}) error {
+	var (
+		histories []History
+		identity  Identity
+		traceID   string
+		now       = time.Now().Unix()
+	)
+
+	identity, _ = IdentityFrom(ctx)
+	if t := trace.Get(ctx); t != nil {
+		traceID = t.TraceID
+	}
+
+	for id, value := range after {
+		var (
+			old  = before[id]
+			diff = diffSnapshot(old, value)
+		)
+		if old != nil && len(diff) == 0 {
+			continue
+		}
+
+		var history = History{
+			Table:     d.model.TableName(),
+			RecordID:  id,
+			Operation: op,
+			ActorID:   identity.ID,
+			Actor:     identity.Name,
+			TraceID:   traceID,
+			CreatedAt: now,
+		}
+		history.After = marshalString(value)
+		history.Diff = marshalString(diff)
+		if old != nil {
+			history.Before = marshalString(old)
+		}
+		histories = append(histories, history)
+	}
+
+	if len(histories) == 0 {
+		return nil
+	}
+
+	return tx.Session(&gorm.Session{NewDB: true}).Create(&histories).Error
+}
+
+// 变更的字段 => [变更前, 变更后]
+func diffSnapshot(before, after map[string]interface{}) map[string][2]interface{} {
+	var diff = make(map[string][2]interface{})
+	for key, value := range after {
+		if old, exists := before[key]; !exists || !reflect.DeepEqual(old, value) {
+			diff[key] = [2]interface{}{old, value}
+		}
+	}
+	for key, old := range before {
+		if _, exists := after[key]; !exists {
+			diff[key] = [2]interface{}{old, nil}
+		}
+	}
+	return diff
+}
+
+func marshalString(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func toInterfaces(list []string) []interface{} {
+	var values = make([]interface{}, len(list))
+	for i, value := range list {
+		values[i] = value
+	}
+	return values
+}
@@ -0,0 +1,145 @@
+package micro
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zooyer/miskit/trace"
+	"gorm.io/gorm"
+)
+
+func TestDao_History(t *testing.T) {
+	db, err := OpenDB("sqlite", ":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(new(testModel), new(History)); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ctx = WithIdentity(context.Background(), Identity{ID: "1", Name: "alice"})
+		dao = NewTypedDao[testModel](func(ctx context.Context) *gorm.DB {
+			return db.WithContext(ctx)
+		}).WithHistory(true)
+	)
+	ctx = trace.Set(ctx, &trace.Trace{TraceID: "trace-1"})
+
+	var dog = testModel{Name: "dog", Age: 1}
+	assert.NoError(t, dao.Create(ctx, &dog))
+	assert.NoError(t, dao.BatchCreate(ctx, []testModel{{Name: "cat"}}, 0))
+	assert.NoError(t, dao.Update(ctx, Equal{"name": "dog"}, Update{"age": 2}))
+	assert.NoError(t, dao.Update(ctx, Equal{"name": "dog"}, Update{"age": 2})) // 无变化不记录
+	assert.NoError(t, dao.Delete(ctx, Equal{"name": "dog"}, Update{}))
+
+	histories, err := dao.Histories(ctx, dog.ID)
+	assert.NoError(t, err)
+	if !assert.Len(t, histories, 3) {
+		return
+	}
+
+	var ops []string
+	for _, history := range histories {
+		ops = append(ops, history.Operation)
+		assert.Equal(t, "test", history.Table)
+		assert.Equal(t, "alice", history.Actor)
+		assert.Equal(t, "1", history.ActorID)
+		assert.Equal(t, "trace-1", history.TraceID)
+	}
+	assert.Equal(t, []string{HistoryCreate, HistoryUpdate, HistoryDelete}, ops)
+	assert.Empty(t, histories[0].Before)
+
+	var diff map[string][2]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(histories[1].Diff), &diff))
+	assert.Equal(t, [2]interface{}{float64(1), float64(2)}, diff["age"])
+	assert.NotContains(t, diff, "name")
+
+	// 恢复到更新后的版本, 撤销软删除
+	assert.NoError(t, dao.Restore(ctx, histories[1].ID))
+	got, err := dao.Get(ctx, dog.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, got.Age)
+	assert.Zero(t, got.DeletedAt)
+
+	// 恢复到创建时的版本
+	assert.NoError(t, dao.Restore(ctx, histories[0].ID))
+	got, err = dao.Get(ctx, dog.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got.Age)
+
+	histories, err = dao.Histories(ctx, dog.ID)
+	assert.NoError(t, err)
+	assert.Len(t, histories, 5)
+	assert.Equal(t, HistoryRestore, histories[4].Operation)
+
+	// 更新失败时历史随事务回滚
+	var count int64
+	db.Model(new(History)).Count(&count)
+	assert.Error(t, dao.Update(ctx, Equal{"name": "dog"}, Update{"unknown": 1}))
+	var after int64
+	db.Model(new(History)).Count(&after)
+	assert.Equal(t, count, after)
+
+	// 未开启时不写入历史
+	assert.NoError(t, dao.WithHistory(false).Update(ctx, Equal{"name": "cat"}, Update{"age": 9}))
+	db.Model(new(History)).Count(&after)
+	assert.Equal(t, count, after)
+}
+
+type secretModel struct {
+	Model
+	Name     string `json:"name"`
+	Remark   string `json:"-"`
+	Password string `json:"-" history:"-"`
+}
+
+func (secretModel) TableName() string {
+	return "secret"
+}
+
+func TestDao_Restore(t *testing.T) {
+	db, err := OpenDB("sqlite", ":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Use(&AuditFields{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(new(secretModel), new(History)); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ctx   = WithIdentity(context.Background(), Identity{ID: "1", Name: "alice"})
+		model = secretModel{Name: "a", Remark: "r1", Password: "secret"}
+		dao   = NewTypedDao[secretModel](func(ctx context.Context) *gorm.DB {
+			return db.WithContext(ctx)
+		}).WithHistory(true)
+	)
+
+	assert.NoError(t, dao.Create(ctx, &model))
+	ctx = WithIdentity(context.Background(), Identity{ID: "2", Name: "bob"})
+	assert.NoError(t, dao.Update(ctx, Equal{"id": model.ID}, Update{"name": "b", "remark": "r2", "password": "changed"}))
+
+	histories, err := dao.Histories(ctx, model.ID)
+	assert.NoError(t, err)
+	if !assert.Len(t, histories, 2) {
+		return
+	}
+	// json:"-"的列记录到快照, history:"-"的列不记录
+	assert.Contains(t, histories[0].After, `"remark":"r1"`)
+	assert.NotContains(t, histories[0].After, "secret")
+	assert.NotContains(t, histories[1].Diff, "changed")
+
+	ctx = WithIdentity(context.Background(), Identity{ID: "3", Name: "carol"})
+	assert.NoError(t, dao.Restore(ctx, histories[0].ID))
+	got, err := dao.Get(ctx, model.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", got.Name)
+	assert.Equal(t, "r1", got.Remark)
+	assert.Equal(t, "changed", got.Password)
+	assert.Equal(t, "alice", got.CreatedBy)
+	assert.Equal(t, "carol", got.UpdatedBy) // updated_*不回退
+}
@@ -0,0 +1,57 @@
+package micro
+
+import (
+	"context"
+	"sync"
+)
+
+// Identity 操作人身份, 用于填充created/updated/deleted的by和id字段
+type Identity struct {
+	ID   string `json:"id"`   // 用户ID
+	Name string `json:"name"` // 用户名
+}
+
+// IdentityResolver 从ctx解析操作人身份
+type IdentityResolver func(ctx context.Context) (Identity, bool)
+
+type identityKey struct{}
+
+var (
+	resolvers     []IdentityResolver
+	resolverMutex sync.RWMutex
+)
+
+// WithIdentity 在ctx中设置操作人身份, 优先于IdentityResolver
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// RegisterIdentityResolver 注册身份解析器, 按注册顺序解析, 如sso.Client.Identity
+func RegisterIdentityResolver(resolver ...IdentityResolver) {
+	resolverMutex.Lock()
+	defer resolverMutex.Unlock()
+
+	resolvers = append(resolvers, resolver...)
+}
+
+// IdentityFrom 获取操作人身份, 先取WithIdentity设置的身份, 再依次尝试注册的解析器
+func IdentityFrom(ctx context.Context) (Identity, bool) {
+	if ctx == nil {
+		return Identity{}, false
+	}
+
+	if identity, ok := ctx.Value(identityKey{}).(Identity); ok {
+		return identity, true
+	}
+
+	resolverMutex.RLock()
+	defer resolverMutex.RUnlock()
+
+	for _, resolver := range resolvers {
+		if identity, ok := resolver(ctx); ok {
+			return identity, true
+		}
+	}
+
+	return Identity{}, false
+}
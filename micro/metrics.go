@@ -0,0 +1,88 @@
+package micro
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zooyer/miskit/metric"
+)
+
+// 未匹配路由的route标签, 避免原始路径导致标签基数膨胀
+const unmatchedRoute = "unmatched"
+
+var (
+	httpLabels = []string{"method", "route", "status"}
+
+	httpRequests = metric.Default.Counter(metric.Opts{
+		Name:   "http_server_requests_total",
+		Help:   "Total number of HTTP requests.",
+		Labels: httpLabels,
+	})
+	httpErrors = metric.Default.Counter(metric.Opts{
+		Name:   "http_server_errors_total",
+		Help:   "Total number of HTTP requests with 5xx status.",
+		Labels: httpLabels,
+	})
+	httpLatency = metric.Default.Histogram(metric.Opts{
+		Name:   "http_server_request_duration_seconds",
+		Help:   "HTTP request latency in seconds.",
+		Labels: httpLabels,
+	})
+	httpInFlight = metric.Default.Gauge(metric.Opts{
+		Name:   "http_server_requests_in_flight",
+		Help:   "Number of HTTP requests being served.",
+		Labels: []string{"method", "route"},
+	})
+)
+
+// MetricsHandler 暴露Prometheus/OpenMetrics指标, registry为nil时使用metric.Default
+//
+//	engine.GET("/metrics", micro.MetricsHandler(nil))
+func MetricsHandler(registry *metric.Registry) gin.HandlerFunc {
+	return gin.WrapH(metric.Handler(registry))
+}
+
+// Metrics 记录请求数、错误数、耗时和处理中请求数(RED), 以路由模板、方法和状态码分类为标签
+func Metrics() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var (
+			start  = time.Now()
+			method = ctx.Request.Method
+			route  = ctx.FullPath()
+		)
+
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		var inFlight = httpInFlight.WithLabelValues(method, route)
+		inFlight.Inc()
+
+		var code = http.StatusInternalServerError
+		defer func() {
+			var status = statusClass(code)
+
+			inFlight.Dec()
+			httpRequests.WithLabelValues(method, route, status).Inc()
+			httpLatency.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+			if code >= http.StatusInternalServerError {
+				httpErrors.WithLabelValues(method, route, status).Inc()
+			}
+		}()
+
+		ctx.Next()
+
+		// panic时保持500
+		code = ctx.Writer.Status()
+	}
+}
+
+// 状态码分类: 2xx/3xx/4xx/5xx
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
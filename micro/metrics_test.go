@@ -0,0 +1,69 @@
+package micro
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/zooyer/miskit/log"
+	"github.com/zooyer/miskit/metric"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	var registry = metric.NewRegistry()
+	registry.Counter(metric.Opts{Name: "test_total", Labels: []string{"name"}}).WithLabelValues("micro").Inc()
+
+	engine := gin.New()
+	engine.GET("/metrics", MetricsHandler(registry))
+
+	resp := get(engine, "/metrics")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, metric.ContentTypeText, resp.Header().Get("Content-Type"))
+	assert.Equal(t, "# TYPE test_total counter\ntest_total{name=\"micro\"} 1\n", resp.Body.String())
+}
+
+func TestMetrics(t *testing.T) {
+	var (
+		config = log.Config{
+			Level: "DEBUG",
+		}
+		logger, _ = log.New(config, nil)
+	)
+
+	engine := gin.New()
+	engine.Use(Recover(logger), Metrics())
+	engine.GET("/metrics/user/:id", func(ctx *gin.Context) {
+		assert.Equal(t, float64(1), httpInFlight.WithLabelValues(http.MethodGet, "/metrics/user/:id").Value())
+		ctx.JSON(http.StatusOK, ctx.Param("id"))
+	})
+	engine.GET("/metrics/panic", func(ctx *gin.Context) {
+		panic("test")
+	})
+
+	get(engine, "/metrics/user/1")
+	get(engine, "/metrics/user/2")
+	get(engine, "/metrics/panic")
+	get(engine, "/metrics/not/found")
+
+	assert.Equal(t, float64(2), httpRequests.WithLabelValues(http.MethodGet, "/metrics/user/:id", "2xx").Value())
+	assert.Equal(t, float64(0), httpInFlight.WithLabelValues(http.MethodGet, "/metrics/user/:id").Value())
+	assert.Equal(t, float64(1), httpRequests.WithLabelValues(http.MethodGet, "/metrics/panic", "5xx").Value())
+	assert.Equal(t, float64(1), httpErrors.WithLabelValues(http.MethodGet, "/metrics/panic", "5xx").Value())
+	assert.Equal(t, float64(1), httpRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "4xx").Value())
+
+	resp := get(engine, "/metrics/user/3")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var buf = new(strings.Builder)
+	assert.NoError(t, metric.WriteText(buf, metric.Default.Gather()))
+	assert.Contains(t, buf.String(), `http_server_request_duration_seconds_count{method="GET",route="/metrics/user/:id",status="2xx"} 3`)
+}
+
+func TestStatusClass(t *testing.T) {
+	assert.Equal(t, "2xx", statusClass(200))
+	assert.Equal(t, "4xx", statusClass(404))
+	assert.Equal(t, "5xx", statusClass(503))
+	assert.Equal(t, "unknown", statusClass(0))
+}
@@ -85,5 +85,33 @@ func Recover(logger *log.Logger) gin.HandlerFunc {
 func Trace(callee string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		trace.Set(ctx, trace.New(ctx.Request, callee))
+
+		// 服务端span, 以路由模板命名
+		var route, name = ctx.FullPath(), ctx.Request.Method
+		if route != "" {
+			name += " " + route
+		}
+		_, span := trace.StartSpan(ctx, name, trace.WithKind(trace.KindServer), trace.WithAttributes(map[string]interface{}{
+			"http.method": ctx.Request.Method,
+			"http.route":  route,
+			"http.target": ctx.Request.URL.Path,
+			"client.ip":   ctx.ClientIP(),
+		}))
+		trace.SetSpan(ctx, span)
+
+		defer func() {
+			if e := recover(); e != nil {
+				span.SetStatus(trace.StatusError, fmt.Sprint(e))
+				span.End()
+				panic(e)
+			}
+		}()
+
+		ctx.Next()
+
+		if err := ctx.Errors.Last(); err != nil {
+			span.RecordError(err)
+		}
+		trace.EndHTTPSpan(span, ctx.Writer.Status())
 	}
 }
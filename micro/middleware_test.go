@@ -1,6 +1,7 @@
 package micro
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 	"testing"
@@ -26,6 +27,31 @@ func TestTrace(t *testing.T) {
 	t.Log(string(data))
 }
 
+func TestTrace_Sampling(t *testing.T) {
+	var exporter = trace.NewMemoryExporter()
+	trace.RegisterProcessor(trace.NewSimpleProcessor(exporter))
+	defer trace.Shutdown(context.Background())
+	defer trace.SetSampler(trace.GetSampler())
+	trace.SetSampler(trace.ParentBased(trace.NeverSample()))
+
+	engine := gin.New()
+	engine.Use(Trace("test"))
+	engine.GET("/trace", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, trace.Get(ctx).Sampled())
+	})
+
+	resp := get(engine, "/trace")
+	assert.Equal(t, "false", resp.Body.String())
+	assert.Len(t, exporter.Spans(), 0)
+
+	resp = get(engine, "/trace", http.Header{"Traceparent": {"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}})
+	assert.Equal(t, "true", resp.Body.String())
+	if spans := exporter.Spans(); assert.Len(t, spans, 1) {
+		assert.Equal(t, "GET /trace", spans[0].Name)
+		assert.Equal(t, "b7ad6b7169203331", spans[0].ParentID)
+	}
+}
+
 func TestLogger(t *testing.T) {
 	var (
 		config = log.Config{
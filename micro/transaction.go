@@ -0,0 +1,134 @@
+package micro
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// TxOption 事务选项
+type TxOption func(options *sql.TxOptions)
+
+// 事务状态, 通过ctx传递
+type txState struct {
+	db     *gorm.DB
+	pool   gorm.ConnPool // 数据库连接池, 区分不同数据库(gorm.Session会复制Config)
+	parent *txState      // 同一数据库的外层事务(当前为savepoint)
+	outer  *txState      // ctx中的上一个事务(可能是其他数据库)
+	mutex  sync.Mutex
+	hooks  []func()
+}
+
+type txKey struct{}
+
+// ReadOnly 只读事务, 嵌套事务中忽略
+func ReadOnly() TxOption {
+	return func(options *sql.TxOptions) {
+		options.ReadOnly = true
+	}
+}
+
+// Isolation 事务隔离级别, 嵌套事务中忽略
+func Isolation(level sql.IsolationLevel) TxOption {
+	return func(options *sql.TxOptions) {
+		options.Isolation = level
+	}
+}
+
+// Transaction 在事务中执行fn, 事务存入ctx, 使用同一数据库的Dao自动加入该事务;
+// fn返回错误或panic时回滚, 嵌套调用时使用savepoint, 内层回滚不影响外层
+//
+//	err = micro.Transaction(ctx, db, func(ctx context.Context) error {
+//		if err := orderDao.Create(ctx, nil, &order); err != nil {
+//			return err
+//		}
+//		micro.AfterCommit(ctx, func() { _ = queue.Pub(...) })
+//		return stockDao.Update(ctx, equal, update)
+//	})
+func Transaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error, options ...TxOption) (err error) {
+	var (
+		parent = txFrom(ctx, db)
+		state  = &txState{parent: parent, pool: db.Config.ConnPool}
+		opts   []*sql.TxOptions
+	)
+	state.outer, _ = ctx.Value(txKey{}).(*txState)
+
+	if parent != nil {
+		db = parent.db
+	} else if len(options) > 0 {
+		var opt sql.TxOptions
+		for _, option := range options {
+			option(&opt)
+		}
+		opts = append(opts, &opt)
+	}
+
+	if err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		state.db = tx
+		return fn(context.WithValue(ctx, txKey{}, state))
+	}, opts...); err != nil {
+		return
+	}
+
+	// savepoint提交后由外层事务提交时执行
+	if parent != nil {
+		parent.addHooks(state.takeHooks()...)
+		return
+	}
+
+	for _, hook := range state.takeHooks() {
+		hook()
+	}
+
+	return
+}
+
+// AfterCommit 注册事务提交后执行的函数(如发送消息), 事务回滚时丢弃, ctx中无事务时立即执行
+func AfterCommit(ctx context.Context, hook func()) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.addHooks(hook)
+		return
+	}
+
+	hook()
+}
+
+// InTransaction ctx中是否有事务
+func InTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*txState)
+	return ok
+}
+
+// 查找ctx中与db为同一数据库的事务
+func txFrom(ctx context.Context, db *gorm.DB) *txState {
+	if ctx == nil || db == nil {
+		return nil
+	}
+
+	state, _ := ctx.Value(txKey{}).(*txState)
+	for ; state != nil; state = state.outer {
+		if state.pool == db.Config.ConnPool {
+			return state
+		}
+	}
+
+	return nil
+}
+
+func (s *txState) addHooks(hooks ...func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.hooks = append(s.hooks, hooks...)
+}
+
+func (s *txState) takeHooks() []func() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var hooks = s.hooks
+	s.hooks = nil
+	return hooks
+}
@@ -0,0 +1,116 @@
+package micro
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTransaction(t *testing.T) {
+	db, err := OpenDB("sqlite", "file:transaction?mode=memory&cache=shared", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(new(testModel), new(filterModel)); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ctx    = context.Background()
+		getDB  = func(ctx context.Context) *gorm.DB { return db.WithContext(ctx) }
+		tests  = NewTypedDao[testModel](getDB)
+		filter = NewTypedDao[filterModel](getDB)
+		hooks  []string
+		count  = func() (n int64) {
+			c1, _ := tests.Count(ctx, nil)
+			c2, _ := filter.Count(ctx, nil)
+			return c1 + c2
+		}
+	)
+
+	// 回滚时两个Dao的写入都撤销, 提交后钩子不执行
+	var errRollback = errors.New("rollback")
+	err = Transaction(ctx, db, func(ctx context.Context) error {
+		assert.True(t, InTransaction(ctx))
+		assert.NoError(t, tests.Create(ctx, &testModel{Name: "a"}))
+		assert.NoError(t, filter.Create(ctx, &filterModel{Name: "a"}))
+		AfterCommit(ctx, func() { hooks = append(hooks, "rollback") })
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+	assert.Equal(t, int64(0), count())
+	assert.Empty(t, hooks)
+
+	// 嵌套事务为savepoint, 内层回滚不影响外层, 内层的钩子丢弃
+	err = Transaction(ctx, db, func(ctx context.Context) error {
+		assert.NoError(t, tests.Create(ctx, &testModel{Name: "outer"}))
+		AfterCommit(ctx, func() { hooks = append(hooks, "outer") })
+
+		assert.Error(t, Transaction(ctx, db, func(ctx context.Context) error {
+			assert.NoError(t, filter.Create(ctx, &filterModel{Name: "inner"}))
+			AfterCommit(ctx, func() { hooks = append(hooks, "inner rollback") })
+			return errRollback
+		}))
+
+		assert.NoError(t, Transaction(ctx, db, func(ctx context.Context) error {
+			AfterCommit(ctx, func() { hooks = append(hooks, "inner") })
+			return filter.Create(ctx, &filterModel{Name: "inner"})
+		}))
+
+		// 提交前钩子不执行
+		assert.Empty(t, hooks)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count())
+	assert.Equal(t, []string{"outer", "inner"}, hooks)
+
+	// 无事务时立即执行
+	hooks = nil
+	AfterCommit(ctx, func() { hooks = append(hooks, "now") })
+	assert.Equal(t, []string{"now"}, hooks)
+	assert.False(t, InTransaction(ctx))
+
+	// panic时回滚
+	assert.Panics(t, func() {
+		_ = Transaction(ctx, db, func(ctx context.Context) error {
+			_ = tests.Create(ctx, &testModel{Name: "panic"})
+			panic("boom")
+		})
+	})
+	assert.Equal(t, int64(2), count())
+
+	// 只读事务
+	err = Transaction(ctx, db, func(ctx context.Context) error {
+		_, err := tests.Count(ctx, nil)
+		return err
+	}, ReadOnly())
+	assert.NoError(t, err)
+}
+
+func TestTransaction_OtherDB(t *testing.T) {
+	db1, err := OpenDB("sqlite", ":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db2, err := OpenDB("sqlite", ":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ctx = context.Background()
+	_ = Transaction(ctx, db1, func(ctx context.Context) error {
+		assert.NotNil(t, txFrom(ctx, db1))
+		assert.Nil(t, txFrom(ctx, db2))
+
+		return Transaction(ctx, db2, func(ctx context.Context) error {
+			assert.NotNil(t, txFrom(ctx, db1))
+			assert.NotNil(t, txFrom(ctx, db2))
+			assert.NotSame(t, txFrom(ctx, db1), txFrom(ctx, db2))
+			return nil
+		})
+	})
+}
@@ -0,0 +1,44 @@
+package micro
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zooyer/miskit/errors"
+	"gorm.io/gorm"
+)
+
+// VersionModel 版本号(乐观锁), 按需嵌入模型, 配合Dao.UpdateWithVersion和Controller的ETag使用
+type VersionModel struct {
+	Version int64 `json:"version" gorm:"not null;default:0"`
+}
+
+// 版本号列名
+const versionColumn = "version"
+
+// UpdateWithVersion 按版本号更新, 成功后版本号加1;
+// 版本号不一致时返回errors.Conflict, 记录不存在时返回gorm.ErrRecordNotFound.
+// Conflict默认响应200, 需要409时由调用方注册映射:
+//
+//	micro.RegisterErrorCode(map[int]int{errors.Conflict: http.StatusConflict})
+func (d Dao) UpdateWithVersion(ctx context.Context, equal Equal, version int64, update Update) (err error) {
+	var (
+		cond   = equal.Merge(Equal{versionColumn: version})
+		values = update.Merge(Update{versionColumn: gorm.Expr(versionColumn + " + 1")})
+	)
+
+	rows, err := d.update(ctx, cond, values, HistoryUpdate)
+	if err != nil || rows > 0 {
+		return
+	}
+
+	count, err := d.Count(ctx, equal)
+	if err != nil {
+		return
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return errors.New(errors.Conflict, fmt.Errorf("version %d is stale", version))
+}
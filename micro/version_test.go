@@ -0,0 +1,217 @@
+package micro
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/zooyer/miskit/errors"
+	"gorm.io/gorm"
+)
+
+type versionModel struct {
+	Model
+	VersionModel
+	Name string `json:"name"`
+}
+
+func (versionModel) TableName() string {
+	return "version"
+}
+
+type versionController struct {
+	Controller
+	dao TypedDao[versionModel]
+}
+
+func (v versionController) Get(ctx *gin.Context) {
+	var (
+		err   error
+		model *versionModel
+	)
+
+	defer func() {
+		if !ctx.IsAborted() {
+			v.Response(ctx, model, err)
+		}
+	}()
+
+	id, err := v.ParamInt(ctx, "id")
+	if err != nil {
+		return
+	}
+
+	if model, err = v.dao.Get(ctx, id); err != nil {
+		return
+	}
+
+	v.NotModified(ctx, model.Version)
+}
+
+func (v versionController) Put(ctx *gin.Context) {
+	var (
+		err   error
+		model versionModel
+	)
+
+	defer func() { v.Response(ctx, nil, err) }()
+
+	id, err := v.ParamInt(ctx, "id")
+	if err != nil {
+		return
+	}
+
+	version, ok, err := v.IfMatch(ctx)
+	if err != nil {
+		return
+	}
+	if !ok {
+		err = errors.New(errors.InvalidRequest, nil)
+		return
+	}
+
+	if err = v.Bind(ctx, &model); err != nil {
+		return
+	}
+
+	err = v.dao.UpdateWithVersion(ctx, Equal{"id": id}, version, Update{"name": model.Name})
+}
+
+func init() {
+	RegisterErrorCode(map[int]int{errors.Conflict: http.StatusConflict})
+}
+
+func newVersionDao(t *testing.T) TypedDao[versionModel] {
+	db, err := OpenDB("sqlite", ":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(new(versionModel), new(History)); err != nil {
+		t.Fatal(err)
+	}
+
+	return NewTypedDao[versionModel](func(ctx context.Context) *gorm.DB {
+		return db.WithContext(ctx)
+	})
+}
+
+func TestDao_UpdateWithVersion(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		dao   = newVersionDao(t)
+		model = versionModel{Name: "a"}
+	)
+
+	assert.NoError(t, dao.Create(ctx, &model))
+	assert.Equal(t, int64(0), model.Version)
+
+	assert.NoError(t, dao.UpdateWithVersion(ctx, Equal{"id": model.ID}, 0, Update{"name": "b"}))
+
+	got, err := dao.Get(ctx, model.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", got.Name)
+	assert.Equal(t, int64(1), got.Version)
+
+	// 版本号过期
+	err = dao.UpdateWithVersion(ctx, Equal{"id": model.ID}, 0, Update{"name": "c"})
+	assert.True(t, errors.Is(err, errors.Conflict), err)
+	got, _ = dao.Get(ctx, model.ID)
+	assert.Equal(t, "b", got.Name)
+
+	// 记录不存在
+	err = dao.UpdateWithVersion(ctx, Equal{"id": 100}, 0, Update{"name": "c"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// 开启变更历史
+	assert.NoError(t, dao.WithHistory(true).UpdateWithVersion(ctx, Equal{"id": model.ID}, 1, Update{"name": "d"}))
+	err = dao.WithHistory(true).UpdateWithVersion(ctx, Equal{"id": model.ID}, 1, Update{"name": "e"})
+	assert.True(t, errors.Is(err, errors.Conflict), err)
+}
+
+func TestDao_RestoreVersion(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		dao   = newVersionDao(t).WithHistory(true)
+		model = versionModel{Name: "a"}
+	)
+
+	assert.NoError(t, dao.Create(ctx, &model))
+	assert.NoError(t, dao.UpdateWithVersion(ctx, Equal{"id": model.ID}, 0, Update{"name": "b"}))
+
+	histories, err := dao.Histories(ctx, model.ID)
+	assert.NoError(t, err)
+	assert.Len(t, histories, 2)
+
+	// 恢复到创建时的版本, 版本号递增而不是回退到0
+	assert.NoError(t, dao.Restore(ctx, histories[0].ID))
+	got, err := dao.Get(ctx, model.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", got.Name)
+	assert.Equal(t, int64(2), got.Version)
+
+	// 恢复前读取的过期版本号不能更新成功
+	for _, version := range []int64{0, 1} {
+		err = dao.UpdateWithVersion(ctx, Equal{"id": model.ID}, version, Update{"name": "c"})
+		assert.True(t, errors.Is(err, errors.Conflict), err)
+	}
+	assert.NoError(t, dao.UpdateWithVersion(ctx, Equal{"id": model.ID}, 2, Update{"name": "c"}))
+}
+
+func TestController_ETag(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		dao        = newVersionDao(t)
+		engine     = gin.New()
+		controller = versionController{dao: dao}
+		model      = versionModel{Name: "a"}
+	)
+
+	assert.NoError(t, dao.Create(ctx, &model))
+
+	engine.GET("/version/:id", controller.Get)
+	engine.PUT("/version/:id", controller.Put)
+
+	var put = func(etag, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/version/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if etag != "" {
+			req.Header.Set("If-Match", etag)
+		}
+		res := httptest.NewRecorder()
+		engine.ServeHTTP(res, req)
+		return res
+	}
+
+	resp := get(engine, "/version/1")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"0"`, resp.Header().Get("ETag"))
+
+	resp = get(engine, "/version/1", http.Header{"If-None-Match": {`W/"0"`}})
+	assert.Equal(t, http.StatusNotModified, resp.Code)
+
+	resp = put(`"0"`, `{"name":"b"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// 使用过期的ETag更新
+	resp = put(`"0"`, `{"name":"c"}`)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	var body Response
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, errors.Conflict, body.Errno)
+
+	// 缺少或无效的If-Match
+	for _, etag := range []string{"", `"x"`, `"1", "2"`} {
+		resp = put(etag, `{"name":"c"}`)
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, errors.InvalidRequest, body.Errno, etag)
+	}
+
+	resp = get(engine, "/version/1", http.Header{"If-None-Match": {`"0"`}})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"1"`, resp.Header().Get("ETag"))
+}
@@ -235,6 +235,17 @@ func TestWechat(t *testing.T) {
 		})
 	)
 
+	cassette, err := zrpc.NewCassette("test/wechat_refresh_token.yaml", zrpc.ModeAuto,
+		zrpc.WithStrict(true),
+		zrpc.WithScrubQuery("secret", "refresh_token"),
+		zrpc.WithScrubJSON("access_token", "refresh_token"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cassette.Save()
+	rpc.SetTransport(cassette)
+
 	var client = NewClient(rpc, config)
 	if step == 1 {
 		t.Log(client.AuthCodeURL(ctx))
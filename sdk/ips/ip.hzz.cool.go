@@ -2,8 +2,6 @@ package ips
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"github.com/zooyer/miskit/log"
 	"github.com/zooyer/miskit/zrpc"
 	url2 "net/url"
@@ -33,36 +31,28 @@ type IP struct {
 }
 
 func New(option Option) *Client {
+	var client = zrpc.New("ips", option.Retry, option.Timeout, option.Logger)
+
+	// 响应格式: {code,msg,data}
+	client.SetEnvelope(zrpc.CodeEnvelope(0, 200))
+
 	return &Client{
 		url:    "https://ip.hzz.cool",
 		option: option,
-		client: zrpc.New("ips", option.Retry, option.Timeout, option.Logger),
+		client: client,
 	}
 }
 
 func (c *Client) QueryIP(ctx context.Context, ip string) (_ *IP, err error) {
-	var resp struct {
-		Code int    `json:"code"`
-		Msg  string `json:"msg"`
-		Data IP     `json:"data"`
-	}
+	var resp IP
 
 	var req = url2.Values{
 		"ip": {ip},
 	}
 
-	data, _, err := c.client.Get(ctx, c.url, req, nil)
-	if err != nil {
-		return
-	}
-
-	if err = json.Unmarshal(data, &resp); err != nil {
+	if _, _, err = c.client.Get(ctx, c.url, req, &resp); err != nil {
 		return
 	}
 
-	if resp.Code != 0 && resp.Code != 200 {
-		return nil, fmt.Errorf("query ip fail, code: %d, msg:%s", resp.Code, resp.Msg)
-	}
-
-	return &resp.Data, nil
+	return &resp, nil
 }
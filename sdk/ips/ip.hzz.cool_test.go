@@ -57,6 +57,13 @@ func TestClient_QueryIP(t *testing.T) {
 				Logger:  logger,
 				Timeout: time.Second,
 			})
+			cassette, err := zrpc.NewCassette("test/query_ip.yaml", zrpc.ModeAuto, zrpc.WithStrict(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cassette.Save()
+			c.client.SetTransport(cassette)
+
 			got, err := c.QueryIP(tt.args.ctx, tt.args.ip)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("QueryIP() error = %v, wantErr %v", err, tt.wantErr)
@@ -6,11 +6,13 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/zooyer/miskit/log"
+	"github.com/zooyer/miskit/micro"
 	"github.com/zooyer/miskit/zrpc"
 )
 
@@ -455,6 +457,21 @@ func (c *Client) SessionUserinfo(ctx context.Context) *Userinfo {
 	return nil
 }
 
+// Identity 会话用户身份, 可注册为micro的身份解析器, 用于自动填充操作人字段
+//
+//	micro.RegisterIdentityResolver(client.Identity)
+func (c *Client) Identity(ctx context.Context) (micro.Identity, bool) {
+	var userinfo = c.SessionUserinfo(ctx)
+	if userinfo == nil {
+		return micro.Identity{}, false
+	}
+
+	return micro.Identity{
+		ID:   strconv.FormatInt(userinfo.UserID, 10),
+		Name: userinfo.Username,
+	}, true
+}
+
 func (c *Client) Pages() *Pages {
 	return (*Pages)(c)
 }
@@ -1,11 +1,16 @@
 package sso
 
 import (
+	"context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/zooyer/miskit/micro"
+	"github.com/zooyer/miskit/zrpc"
 )
 
 func TestSession(t *testing.T) {
@@ -38,3 +43,54 @@ func TestSession(t *testing.T) {
 
 	panic(engine.Run())
 }
+
+func TestClient_Identity(t *testing.T) {
+	var (
+		client = New(Option{ClientID: "test"})
+		ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	)
+
+	_, ok := client.Identity(ctx)
+	assert.False(t, ok)
+
+	ctx.Set(client.contextKey(), &Userinfo{UserID: 7, Username: "zs"})
+	identity, ok := client.Identity(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, micro.Identity{ID: "7", Name: "zs"}, identity)
+}
+
+func TestClient_OAuth(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		client = New(Option{
+			ClientID:     "5dbeab91e4904b7bae78b7e4408ceed7",
+			ClientSecret: "5f1766594bf8431d87a61c71f0f859e8",
+			Addr:         "http://127.0.0.1:8801",
+			Timeout:      time.Second * 2,
+		})
+	)
+
+	cassette, err := zrpc.NewCassette("test/oauth.yaml", zrpc.ModeAuto, zrpc.WithStrict(true),
+		zrpc.WithScrubJSON("client_secret", "access_token", "refresh_token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cassette.Save()
+	client.client.SetTransport(cassette)
+
+	token, err := client.Token(ctx, "ZGVtby1jb2Rl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Bearer", token.TokenType)
+	assert.Equal(t, int64(7200), token.ExpiresIn)
+
+	assert.NoError(t, client.Verify(ctx, "access-token"))
+
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	userinfo, err := client.Userinfo(ginCtx, "access-token")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(996), userinfo.UserID)
+	assert.Equal(t, "http://127.0.0.1:8801/sso/avatar/996.png", userinfo.UserAvatar)
+}
@@ -3,6 +3,7 @@ package ice
 import (
 	"context"
 	"github.com/zooyer/miskit/log"
+	"github.com/zooyer/miskit/zrpc"
 	"testing"
 	"time"
 )
@@ -27,6 +28,13 @@ func TestNew(t *testing.T) {
 	}
 	upm := New(option)
 
+	cassette, err := zrpc.NewCassette("test/auth.yaml", zrpc.ModeAuto, zrpc.WithStrict(true), zrpc.WithScrubJSON("app_secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cassette.Save()
+	upm.client.SetTransport(cassette)
+
 	cond := map[string]interface{}{
 		"path":   "/upm/v1/test",
 		"method": "GET",
@@ -1,8 +1,10 @@
 package smq
 
 import (
+	"context"
 	"fmt"
 	"github.com/streadway/amqp"
+	"github.com/zooyer/miskit/trace"
 	"sync"
 )
 
@@ -21,6 +23,33 @@ type rabbit struct {
 	connection *amqp.Connection
 }
 
+// AMQPCarrier AMQP消息头trace载体
+type AMQPCarrier amqp.Table
+
+func (a AMQPCarrier) Get(key string) string {
+	switch value := a[key].(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	}
+	return ""
+}
+
+func (a AMQPCarrier) Set(key, value string) {
+	a[key] = value
+}
+
+// InjectAMQP 写入trace到消息头
+func InjectAMQP(t *trace.Trace, headers amqp.Table) {
+	t.Inject(AMQPCarrier(headers))
+}
+
+// ExtractAMQP 从消息头读取trace, 没有时创建新trace
+func ExtractAMQP(headers amqp.Table, callee string) *trace.Trace {
+	return trace.Extract(AMQPCarrier(headers), callee)
+}
+
 func NewRabbitMQ(broker string, exchange Exchange) *rabbit {
 	return &rabbit{
 		broker:   broker,
@@ -81,6 +110,23 @@ func (r *rabbit) Pub(topic string, message []byte, option ...Option) (err error)
 		Body:        message,
 		Expiration:  fmt.Sprint(options.TTL.Milliseconds()),
 	}
+
+	// 生产者span, 消费者以此为父节点
+	if options.Context != nil && trace.Get(options.Context) != nil {
+		_, span := trace.StartSpan(options.Context, r.Name+" publish", trace.WithKind(trace.KindProducer), trace.WithAttributes(map[string]interface{}{
+			"messaging.system":      "rabbitmq",
+			"messaging.destination": r.Name,
+			"messaging.routing_key": r.Key,
+		}))
+		defer func() {
+			span.RecordError(err)
+			span.End()
+		}()
+
+		publish.Headers = make(amqp.Table)
+		InjectAMQP(span.Trace(), publish.Headers)
+	}
+
 	if err = r.channel.Publish(r.Name, r.Key, false, false, publish); err != nil {
 		return
 	}
@@ -116,7 +162,7 @@ func (r *rabbit) Sub(topic string, subscriber Subscriber) (err error) {
 	go func() {
 		for msg := range consume {
 			var multiple bool
-			if err = subscriber(r, topic, msg.Body, Options{}); err != nil {
+			if err = r.consume(topic, msg, subscriber); err != nil {
 				multiple = true
 				// TODO callback error
 			}
@@ -129,6 +175,23 @@ func (r *rabbit) Sub(topic string, subscriber Subscriber) (err error) {
 	return
 }
 
+// 消费消息, 读取消息头中的trace并创建消费者span
+func (r *rabbit) consume(topic string, msg amqp.Delivery, subscriber Subscriber) (err error) {
+	var ctx = trace.Set(context.Background(), ExtractAMQP(msg.Headers, r.Queue))
+
+	ctx, span := trace.StartSpan(ctx, r.Queue+" process", trace.WithKind(trace.KindConsumer), trace.WithAttributes(map[string]interface{}{
+		"messaging.system":      "rabbitmq",
+		"messaging.destination": msg.Exchange,
+		"messaging.routing_key": msg.RoutingKey,
+	}))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	return subscriber(r, topic, msg.Body, Options{Context: ctx})
+}
+
 func (r *rabbit) Close() (err error) {
 	if err = r.channel.Close(); err != nil {
 		return
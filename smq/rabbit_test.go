@@ -1,9 +1,14 @@
 package smq
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/zooyer/miskit/trace"
 )
 
 func TestNewRabbitMQ(t *testing.T) {
@@ -38,3 +43,26 @@ func TestNewRabbitMQ(t *testing.T) {
 
 	<-ch
 }
+
+func TestAMQPCarrier(t *testing.T) {
+	var ctx = trace.Set(context.Background(), trace.New(nil, "producer"))
+	ctx, _ = trace.SetBaggage(ctx, "user_id", "10001")
+
+	var headers = make(amqp.Table)
+	InjectAMQP(trace.Get(ctx), headers)
+
+	// 消息头可能以[]byte传递
+	headers["Z-Lang"] = []byte("zh-CN")
+	headers["Z-Tag"] = 1
+
+	var consumer = ExtractAMQP(headers, "consumer")
+	assert.Equal(t, trace.Get(ctx).TraceID, consumer.TraceID)
+	assert.Equal(t, "producer", consumer.Caller)
+	assert.Equal(t, "consumer", consumer.Callee)
+	assert.Equal(t, "zh-CN", consumer.Lang)
+	assert.Equal(t, "", consumer.Tag)
+	assert.Equal(t, "10001", consumer.Baggage["user_id"])
+
+	// 无消息头时创建新trace
+	assert.NotEmpty(t, ExtractAMQP(nil, "consumer").TraceID)
+}
@@ -1,12 +1,14 @@
 package smq
 
 import (
+	"context"
 	"time"
 )
 
 type Options struct {
-	Ack bool
-	TTL time.Duration
+	Ack     bool
+	TTL     time.Duration
+	Context context.Context // 发布时携带trace的ctx; 订阅时为携带上游trace的ctx
 }
 
 type Option func(ops *Options)
@@ -32,6 +34,13 @@ func Ack(ack bool) Option {
 	}
 }
 
+// Context 发布消息时将ctx中的trace写入消息头
+func Context(ctx context.Context) Option {
+	return func(ops *Options) {
+		ops.Context = ctx
+	}
+}
+
 func options(option ...Option) Options {
 	var options Options
 	for _, opt := range option {
@@ -1,6 +1,7 @@
 package ssh
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/pkg/sftp"
+	"github.com/zooyer/miskit/trace"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -148,6 +150,66 @@ func Command(remote, password, cmd string) (output string, err error) {
 	return CommandSession(session, cmd)
 }
 
+// 会话执行命令, ctx中的trace以环境变量(TRACEPARENT、Z_TRACEID等)传给远端命令
+func CommandSessionContext(ctx context.Context, session *ssh.Session, cmd string) (output string, err error) {
+	var env = traceEnv(ctx)
+
+	// 服务端未允许(AcceptEnv)时改为命令前export
+	for _, kv := range env {
+		var index = strings.IndexByte(kv, '=')
+		if err = session.Setenv(kv[:index], kv[index+1:]); err != nil {
+			cmd = exportEnv(env) + cmd
+			break
+		}
+	}
+
+	return CommandSession(session, cmd)
+}
+
+// 执行命令, ctx中的trace以环境变量传给远端命令
+func CommandContext(ctx context.Context, remote, password, cmd string) (output string, err error) {
+	user, addr, _, err := parse(remote)
+	if err != nil {
+		return
+	}
+
+	client, session, err := Session(user, password, addr)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	defer session.Close()
+
+	return CommandSessionContext(ctx, session, cmd)
+}
+
+// ctx中trace的环境变量列表
+func traceEnv(ctx context.Context) []string {
+	var t = trace.Get(ctx)
+	if t == nil {
+		return nil
+	}
+
+	var carrier = make(trace.EnvCarrier)
+	t.Inject(carrier)
+
+	return carrier.Environ()
+}
+
+// 转为export语句, 值使用单引号转义
+func exportEnv(env []string) string {
+	var builder strings.Builder
+	for _, kv := range env {
+		var index = strings.IndexByte(kv, '=')
+		builder.WriteString("export ")
+		builder.WriteString(kv[:index])
+		builder.WriteString("='")
+		builder.WriteString(strings.ReplaceAll(kv[index+1:], "'", `'\''`))
+		builder.WriteString("'; ")
+	}
+	return builder.String()
+}
+
 func username() string {
 	u, err := user.Current()
 	if err != nil {
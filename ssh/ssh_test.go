@@ -1,8 +1,12 @@
 package ssh
 
 import (
+	"context"
 	"fmt"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zooyer/miskit/trace"
 )
 
 func TestParse(t *testing.T) {
@@ -74,3 +78,18 @@ func TestCmd(t *testing.T) {
 	}
 	t.Log(output)
 }
+
+func TestTraceEnv(t *testing.T) {
+	assert.Nil(t, traceEnv(context.Background()))
+
+	var info = trace.New(nil, "test")
+	info.SpanID = "b7ad6b7169203331"
+	info.Tag = "it's"
+
+	var env = traceEnv(trace.Set(context.Background(), info))
+	assert.Contains(t, env, "Z_TRACEID="+info.TraceID)
+	assert.Contains(t, env, "Z_TAG=it's")
+	assert.Contains(t, env, "TRACEPARENT=00-"+trace.W3CTraceID(info.TraceID)+"-b7ad6b7169203331-01")
+
+	assert.Equal(t, `export A='1'; export B='it'\''s'; `, exportEnv([]string{"A=1", "B=it's"}))
+}
@@ -0,0 +1,69 @@
+package trace
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvCarrier 环境变量载体, key转为大写并以下划线分隔(traceparent -> TRACEPARENT, Z-TraceID -> Z_TRACEID)
+type EnvCarrier map[string]string
+
+// Detach 返回脱离ctx取消和超时的新ctx, 保留trace和span
+func Detach(ctx context.Context) context.Context {
+	var detached = context.Background()
+
+	if trace := Get(ctx); trace != nil {
+		detached = context.WithValue(detached, traceKey, trace.Clone())
+	}
+	if span := SpanFromContext(ctx); span != nil {
+		detached = context.WithValue(detached, spanKey, span)
+	}
+
+	return detached
+}
+
+// Go 启动goroutine, fn的ctx不随原ctx取消(如gin请求结束), 但保留trace
+func Go(ctx context.Context, fn func(ctx context.Context)) {
+	ctx = Detach(ctx)
+	go fn(ctx)
+}
+
+// Environ 解析KEY=VALUE列表(os.Environ格式)为环境变量载体
+func Environ(environ []string) EnvCarrier {
+	var carrier = make(EnvCarrier)
+	for _, env := range environ {
+		if index := strings.IndexByte(env, '='); index > 0 {
+			carrier[env[:index]] = env[index+1:]
+		}
+	}
+	return carrier
+}
+
+// FromEnv 从当前进程环境变量读取trace(由上游进程注入)
+func FromEnv(callee string) *Trace {
+	return Extract(Environ(os.Environ()), callee)
+}
+
+func (e EnvCarrier) Get(key string) string {
+	return e[envKey(key)]
+}
+
+func (e EnvCarrier) Set(key, value string) {
+	e[envKey(key)] = value
+}
+
+// Environ 转为按key排序的KEY=VALUE列表, 可用于exec.Cmd.Env
+func (e EnvCarrier) Environ() []string {
+	var list = make([]string, 0, len(e))
+	for key, value := range e {
+		list = append(list, key+"="+value)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func envKey(key string) string {
+	return strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
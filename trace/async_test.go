@@ -0,0 +1,66 @@
+package trace
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGo(t *testing.T) {
+	var (
+		ctx, _           = gin.CreateTestContext(httptest.NewRecorder())
+		parent           = New(nil, "test")
+		canceled, cancel = context.WithCancel(Set(context.Background(), parent))
+	)
+	Set(ctx, parent)
+	_, span := StartSpan(canceled, "parent")
+	SetSpan(ctx, span)
+	cancel()
+
+	var done = make(chan context.Context)
+	Go(ctx, func(ctx context.Context) {
+		done <- ctx
+	})
+
+	var detached = <-done
+	assert.NoError(t, detached.Err())
+	assert.Equal(t, span.Trace().TraceID, Get(detached).TraceID)
+	assert.Equal(t, span, SpanFromContext(detached))
+
+	// 脱离取消
+	assert.Error(t, canceled.Err())
+	assert.NoError(t, Detach(canceled).Err())
+	assert.Equal(t, parent.TraceID, Get(Detach(canceled)).TraceID)
+	assert.Nil(t, Get(Detach(context.Background())))
+}
+
+func TestEnvCarrier(t *testing.T) {
+	var ctx = Set(context.Background(), New(nil, "test"))
+	ctx, _ = SetBaggage(ctx, "user_id", "10001")
+
+	var carrier = make(EnvCarrier)
+	Get(ctx).Inject(carrier)
+
+	var environ = carrier.Environ()
+	assert.Contains(t, environ, "BAGGAGE=user_id=10001")
+	assert.Contains(t, environ, "Z_TRACEID="+Get(ctx).TraceID)
+
+	var child = Extract(Environ(environ), "child")
+	assert.Equal(t, Get(ctx).TraceID, child.TraceID)
+	assert.NotEmpty(t, child.SpanID)
+	assert.Equal(t, "test", child.Caller)
+	assert.Equal(t, "10001", child.Baggage["user_id"])
+
+	for _, env := range environ {
+		var carrier = Environ([]string{env})
+		for key, value := range carrier {
+			os.Setenv(key, value)
+			defer os.Unsetenv(key)
+		}
+	}
+	assert.Equal(t, Get(ctx).TraceID, FromEnv("child").TraceID)
+}
@@ -0,0 +1,175 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// W3C baggage限制
+const (
+	MaxBaggageMembers     = 64   // 最大成员数量
+	MaxBaggageBytes       = 8192 // 编码后最大长度
+	MaxBaggageMemberBytes = 4096 // 单个成员编码后最大长度
+)
+
+const w3cHeaderBaggage = "baggage"
+
+var (
+	ErrBaggageKey     = errors.New("trace: invalid baggage key")
+	ErrBaggageMembers = errors.New("trace: too many baggage members")
+	ErrBaggageSize    = errors.New("trace: baggage too large")
+)
+
+// SetBaggage 设置baggage, 随trace传递给下游; 返回携带新trace的ctx(Store原地设置), value为空时删除
+func SetBaggage(ctx context.Context, key, value string) (context.Context, error) {
+	if !isToken(key) {
+		return ctx, ErrBaggageKey
+	}
+
+	var trace = Get(ctx).Clone()
+	if trace == nil {
+		trace = New(nil, "")
+	}
+
+	if value == "" {
+		delete(trace.Baggage, key)
+		return Set(ctx, trace), nil
+	}
+
+	if trace.Baggage == nil {
+		trace.Baggage = make(map[string]string)
+	}
+	trace.Baggage[key] = value
+
+	if len(trace.Baggage) > MaxBaggageMembers {
+		return ctx, ErrBaggageMembers
+	}
+	if len(encodeMember(key, value)) > MaxBaggageMemberBytes || len(encodeBaggage(trace.Baggage)) > MaxBaggageBytes {
+		return ctx, ErrBaggageSize
+	}
+
+	return Set(ctx, trace), nil
+}
+
+// Baggage 获取ctx中的baggage副本
+func Baggage(ctx context.Context) map[string]string {
+	var trace = Get(ctx)
+	if trace == nil || len(trace.Baggage) == 0 {
+		return nil
+	}
+
+	var baggage = make(map[string]string, len(trace.Baggage))
+	for key, value := range trace.Baggage {
+		baggage[key] = value
+	}
+
+	return baggage
+}
+
+// BaggageValue 获取单个baggage
+func BaggageValue(ctx context.Context, key string) string {
+	if trace := Get(ctx); trace != nil {
+		return trace.Baggage[key]
+	}
+	return ""
+}
+
+func injectBaggage(trace *Trace, carrier Carrier) {
+	if value := encodeBaggage(trace.Baggage); value != "" {
+		carrier.Set(w3cHeaderBaggage, value)
+	}
+}
+
+func extractBaggage(carrier Carrier, trace *Trace) {
+	if baggage := decodeBaggage(carrier.Get(w3cHeaderBaggage)); len(baggage) > 0 {
+		trace.Baggage = baggage
+	}
+}
+
+// 按key排序编码, 超出限制的成员丢弃
+func encodeBaggage(baggage map[string]string) string {
+	var keys = make([]string, 0, len(baggage))
+	for key := range baggage {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var (
+		builder strings.Builder
+		count   int
+	)
+	for _, key := range keys {
+		var member = encodeMember(key, baggage[key])
+		if len(member) > MaxBaggageMemberBytes {
+			continue
+		}
+		if count == MaxBaggageMembers || builder.Len()+len(member)+1 > MaxBaggageBytes {
+			break
+		}
+		if count > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(member)
+		count++
+	}
+
+	return builder.String()
+}
+
+// 解析baggage请求头, 忽略属性(;后内容)和无效成员
+func decodeBaggage(header string) map[string]string {
+	if header == "" || len(header) > MaxBaggageBytes {
+		return nil
+	}
+
+	var baggage = make(map[string]string)
+	for _, member := range strings.Split(header, ",") {
+		if len(baggage) == MaxBaggageMembers {
+			break
+		}
+
+		if index := strings.IndexByte(member, ';'); index >= 0 {
+			member = member[:index]
+		}
+
+		var index = strings.IndexByte(member, '=')
+		if index < 0 {
+			continue
+		}
+
+		var key = strings.TrimSpace(member[:index])
+		if !isToken(key) {
+			continue
+		}
+
+		value, err := url.PathUnescape(strings.TrimSpace(member[index+1:]))
+		if err != nil || value == "" {
+			continue
+		}
+
+		baggage[key] = value
+	}
+
+	return baggage
+}
+
+func encodeMember(key, value string) string {
+	return key + "=" + url.PathEscape(value)
+}
+
+// RFC7230 token
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		var c = s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
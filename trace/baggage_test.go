@@ -0,0 +1,86 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetBaggage(t *testing.T) {
+	var ctx = Set(context.Background(), New(nil, "test"))
+
+	ctx1, err := SetBaggage(ctx, "user_id", "10001")
+	assert.NoError(t, err)
+	ctx1, err = SetBaggage(ctx1, "tenant", "a b,c")
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"user_id": "10001", "tenant": "a b,c"}, Baggage(ctx1))
+	assert.Equal(t, "10001", BaggageValue(ctx1, "user_id"))
+	// 原ctx不受影响
+	assert.Nil(t, Baggage(ctx))
+
+	// 删除
+	ctx2, err := SetBaggage(ctx1, "tenant", "")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"user_id": "10001"}, Baggage(ctx2))
+
+	// 子trace继承
+	_, span := StartSpan(ctx1, "child")
+	assert.Equal(t, "10001", span.Trace().Baggage["user_id"])
+
+	// 无trace时自动创建
+	ctx3, err := SetBaggage(context.Background(), "key", "value")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, Get(ctx3).TraceID)
+
+	_, err = SetBaggage(ctx, "invalid key", "value")
+	assert.Equal(t, ErrBaggageKey, err)
+
+	_, err = SetBaggage(ctx, "large", strings.Repeat("x", MaxBaggageMemberBytes))
+	assert.Equal(t, ErrBaggageSize, err)
+
+	var limit = ctx
+	for i := 0; i < MaxBaggageMembers; i++ {
+		limit, err = SetBaggage(limit, fmt.Sprint("key", i), "value")
+		assert.NoError(t, err)
+	}
+	_, err = SetBaggage(limit, "overflow", "value")
+	assert.Equal(t, ErrBaggageMembers, err)
+	assert.Len(t, Baggage(limit), MaxBaggageMembers)
+}
+
+func TestBaggage_Propagation(t *testing.T) {
+	var ctx = Set(context.Background(), New(nil, "test"))
+	ctx, _ = SetBaggage(ctx, "user_id", "10001")
+	ctx, _ = SetBaggage(ctx, "tenant", "a b,c=d")
+
+	var header = make(http.Header)
+	Get(ctx).SetHeader(header)
+	assert.Equal(t, "tenant=a%20b%2Cc=d,user_id=10001", header.Get("baggage"))
+
+	var req = &http.Request{Header: header}
+	assert.Equal(t, map[string]string{"user_id": "10001", "tenant": "a b,c=d"}, New(req, "test").Baggage)
+
+	// 仅B3传递时baggage仍可读取
+	defer SetPropagators(Propagators()...)
+	SetPropagators(B3Propagator{})
+	header = make(http.Header)
+	Get(ctx).SetHeader(header)
+	assert.Equal(t, "10001", New(&http.Request{Header: header}, "test").Baggage["user_id"])
+}
+
+func TestDecodeBaggage(t *testing.T) {
+	assert.Equal(t, map[string]string{"key1": "value1", "key2": "value 2"}, decodeBaggage(" key1 = value1 ;prop=1, key2=value%202,invalid,bad key=1,empty=,key3=%zz"))
+	assert.Nil(t, decodeBaggage(""))
+	assert.Nil(t, decodeBaggage(strings.Repeat("k=v,", MaxBaggageBytes)))
+
+	var members []string
+	for i := 0; i < MaxBaggageMembers+10; i++ {
+		members = append(members, fmt.Sprintf("key%d=value", i))
+	}
+	assert.Len(t, decodeBaggage(strings.Join(members, ",")), MaxBaggageMembers)
+}
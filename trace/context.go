@@ -0,0 +1,44 @@
+package trace
+
+import "context"
+
+// Store 可原地存取值的ctx(如*gin.Context), trace和span直接保存在其中而不派生新ctx
+type Store interface {
+	Set(key string, value interface{})
+	Get(key string) (value interface{}, exists bool)
+}
+
+type ctxKey int
+
+const (
+	traceKey ctxKey = iota
+	spanKey
+)
+
+// Store中使用的key
+const (
+	contextKey     = "z-context"
+	spanContextKey = "z-span"
+)
+
+func setValue(ctx context.Context, key ctxKey, name string, value interface{}) context.Context {
+	if store, ok := ctx.(Store); ok {
+		store.Set(name, value)
+		return ctx
+	}
+	return context.WithValue(ctx, key, value)
+}
+
+// 优先读取Store, 未设置时读取ctx链(如net/http中间件写入请求ctx);
+// 由Store派生的ctx(如context.WithTimeout(ginCtx))不再是Store, 通过字符串key经Store的Value方法读取
+func getValue(ctx context.Context, key ctxKey, name string) interface{} {
+	if store, ok := ctx.(Store); ok {
+		if value, exists := store.Get(name); exists {
+			return value
+		}
+	}
+	if value := ctx.Value(key); value != nil {
+		return value
+	}
+	return ctx.Value(name)
+}
@@ -0,0 +1,164 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPOption HTTP导出选项, 零值使用默认值
+type HTTPOption struct {
+	URL     string            // 收集器地址
+	Headers map[string]string // 附加请求头(鉴权等)
+	Timeout time.Duration     // 单次请求超时, 默认10s
+	Retry   int               // 失败重试次数(网络错误、429、5xx), 默认3, 小于0不重试
+	Backoff time.Duration     // 重试初始间隔, 按2倍递增, 默认100ms
+	Client  *http.Client      // 自定义客户端
+}
+
+// FileExporter 文件导出器, 每个span一行JSON(JSON Lines)
+type FileExporter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// 导出请求失败
+type exportError struct {
+	code int
+	body string
+}
+
+// HTTP导出器公共部分: 编码后发送并重试
+type httpExporter struct {
+	option HTTPOption
+	client *http.Client
+	encode func(spans []*SpanData) ([]byte, error)
+}
+
+func (e *exportError) Error() string {
+	return fmt.Sprintf("trace: export failed, status code %d: %s", e.code, e.body)
+}
+
+func newHTTPExporter(option HTTPOption, encode func(spans []*SpanData) ([]byte, error)) *httpExporter {
+	if option.Timeout <= 0 {
+		option.Timeout = 10 * time.Second
+	}
+	if option.Retry == 0 {
+		option.Retry = 3
+	}
+	if option.Backoff <= 0 {
+		option.Backoff = 100 * time.Millisecond
+	}
+
+	var client = option.Client
+	if client == nil {
+		client = &http.Client{Timeout: option.Timeout}
+	}
+
+	return &httpExporter{
+		option: option,
+		client: client,
+		encode: encode,
+	}
+}
+
+func (h *httpExporter) Export(ctx context.Context, spans []*SpanData) (err error) {
+	if len(spans) == 0 {
+		return
+	}
+
+	data, err := h.encode(spans)
+	if err != nil {
+		return
+	}
+
+	var backoff = h.option.Backoff
+	for i := 0; ; i++ {
+		var retry bool
+		if retry, err = h.send(ctx, data); err == nil || !retry || i >= h.option.Retry {
+			return
+		}
+
+		var timer = time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// send 发送一次, 返回是否可重试
+func (h *httpExporter) send(ctx context.Context, data []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.option.URL, bytes.NewReader(data))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range h.option.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	var code = resp.StatusCode
+
+	return code == http.StatusTooManyRequests || code >= 500 && code != http.StatusNotImplemented, &exportError{code: code, body: string(body)}
+}
+
+func (h *httpExporter) Shutdown(ctx context.Context) error {
+	h.client.CloseIdleConnections()
+	return nil
+}
+
+// NewFileExporter 创建文件导出器, writer实现io.Closer时Shutdown会关闭
+func NewFileExporter(writer io.Writer) *FileExporter {
+	return &FileExporter{writer: writer}
+}
+
+func (f *FileExporter) Export(ctx context.Context, spans []*SpanData) error {
+	var buf bytes.Buffer
+	var encoder = json.NewEncoder(&buf)
+	for _, span := range spans {
+		if err := encoder.Encode(span); err != nil {
+			return err
+		}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	_, err := f.writer.Write(buf.Bytes())
+
+	return err
+}
+
+func (f *FileExporter) Shutdown(ctx context.Context) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if closer, ok := f.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSpans() []*SpanData {
+	var start = time.Unix(1600000000, 0)
+	return []*SpanData{
+		{
+			TraceID:    "0af7651916cd43dd8448eb211c80319c",
+			SpanID:     "b7ad6b7169203331",
+			Name:       "GET /user",
+			Service:    "server",
+			Kind:       KindServer,
+			StartTime:  start,
+			EndTime:    start.Add(10 * time.Millisecond),
+			Status:     StatusError,
+			Attributes: map[string]interface{}{"http.status_code": 500, "http.method": "GET"},
+			Events:     []Event{{Name: "exception", Time: start.Add(time.Millisecond)}},
+		},
+		{
+			TraceID:   "0af7651916cd43dd8448eb211c80319c",
+			SpanID:    "00f067aa0ba902b7",
+			ParentID:  "b7ad6b7169203331",
+			Name:      "redis",
+			Service:   "server",
+			StartTime: start.Add(time.Millisecond),
+			EndTime:   start.Add(2 * time.Millisecond),
+		},
+		{
+			TraceID:   "0af7651916cd43dd8448eb211c80319c",
+			SpanID:    "a2fb4a1d1a96d312",
+			Name:      "GET /order",
+			Service:   "client",
+			Kind:      KindClient,
+			StartTime: start,
+			EndTime:   start.Add(time.Millisecond),
+		},
+	}
+}
+
+// collector 模拟收集器, 前fail次返回status
+func collector(t *testing.T, fail int32, status int, body chan<- []byte) (*httptest.Server, *int32) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "token", r.Header.Get("Authorization"))
+		if atomic.AddInt32(&count, 1) <= fail {
+			w.WriteHeader(status)
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		body <- data
+	}))
+	return server, &count
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body = make(chan []byte, 1)
+	server, _ := collector(t, 0, 0, body)
+	defer server.Close()
+
+	var exporter = NewOTLPExporter(HTTPOption{URL: server.URL, Headers: map[string]string{"Authorization": "token"}})
+	assert.NoError(t, exporter.Export(context.Background(), testSpans()))
+	assert.NoError(t, exporter.Shutdown(context.Background()))
+
+	var request struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string
+					Value struct{ StringValue string }
+				}
+			}
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID           string
+					SpanID            string
+					ParentSpanID      string
+					Name              string
+					Kind              int
+					StartTimeUnixNano string
+					EndTimeUnixNano   string
+					Attributes        []struct {
+						Key   string
+						Value map[string]interface{}
+					}
+					Events []struct{ Name string }
+					Status struct{ Code int }
+				}
+			}
+		}
+	}
+	assert.NoError(t, json.Unmarshal(<-body, &request))
+
+	if assert.Len(t, request.ResourceSpans, 2) {
+		var resource = request.ResourceSpans[0]
+		assert.Equal(t, "service.name", resource.Resource.Attributes[0].Key)
+		assert.Equal(t, "server", resource.Resource.Attributes[0].Value.StringValue)
+
+		var spans = resource.ScopeSpans[0].Spans
+		if assert.Len(t, spans, 2) {
+			assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", spans[0].TraceID)
+			assert.Equal(t, "b7ad6b7169203331", spans[0].SpanID)
+			assert.Equal(t, 2, spans[0].Kind)
+			assert.Equal(t, "1600000000000000000", spans[0].StartTimeUnixNano)
+			assert.Equal(t, "1600000000010000000", spans[0].EndTimeUnixNano)
+			assert.Equal(t, "http.method", spans[0].Attributes[0].Key)
+			assert.Equal(t, map[string]interface{}{"stringValue": "GET"}, spans[0].Attributes[0].Value)
+			assert.Equal(t, map[string]interface{}{"intValue": "500"}, spans[0].Attributes[1].Value)
+			assert.Equal(t, "exception", spans[0].Events[0].Name)
+			assert.Equal(t, 2, spans[0].Status.Code)
+			assert.Equal(t, "b7ad6b7169203331", spans[1].ParentSpanID)
+			assert.Equal(t, 1, spans[1].Kind)
+		}
+		assert.Equal(t, "client", request.ResourceSpans[1].Resource.Attributes[0].Value.StringValue)
+		assert.Equal(t, 3, request.ResourceSpans[1].ScopeSpans[0].Spans[0].Kind)
+	}
+}
+
+func TestZipkinExporter(t *testing.T) {
+	var body = make(chan []byte, 1)
+	server, _ := collector(t, 0, 0, body)
+	defer server.Close()
+
+	var exporter = NewZipkinExporter(HTTPOption{URL: server.URL, Headers: map[string]string{"Authorization": "token"}})
+	assert.NoError(t, exporter.Export(context.Background(), testSpans()))
+
+	var spans []zipkinSpan
+	assert.NoError(t, json.Unmarshal(<-body, &spans))
+
+	if assert.Len(t, spans, 3) {
+		assert.Equal(t, zipkinSpan{
+			TraceID:       "0af7651916cd43dd8448eb211c80319c",
+			ID:            "b7ad6b7169203331",
+			Name:          "GET /user",
+			Kind:          "SERVER",
+			Timestamp:     1600000000000000,
+			Duration:      10000,
+			LocalEndpoint: &zipkinEndpoint{ServiceName: "server"},
+			Tags:          map[string]string{"http.status_code": "500", "http.method": "GET", "error": "true"},
+			Annotations:   []zipkinAnnotation{{Timestamp: 1600000000001000, Value: "exception"}},
+		}, spans[0])
+		assert.Equal(t, "b7ad6b7169203331", spans[1].ParentID)
+		assert.Equal(t, "", spans[1].Kind)
+		assert.Equal(t, "CLIENT", spans[2].Kind)
+	}
+}
+
+func TestExporterRetry(t *testing.T) {
+	var body = make(chan []byte, 1)
+	server, count := collector(t, 2, http.StatusServiceUnavailable, body)
+	defer server.Close()
+
+	var option = HTTPOption{URL: server.URL, Headers: map[string]string{"Authorization": "token"}, Backoff: time.Millisecond}
+	assert.NoError(t, NewOTLPExporter(option).Export(context.Background(), testSpans()))
+	assert.Equal(t, int32(3), atomic.LoadInt32(count))
+	assert.NotEmpty(t, <-body)
+
+	// 4xx不重试
+	server, count = collector(t, 10, http.StatusBadRequest, body)
+	defer server.Close()
+
+	option.URL = server.URL
+	assert.Error(t, NewZipkinExporter(option).Export(context.Background(), testSpans()))
+	assert.Equal(t, int32(1), atomic.LoadInt32(count))
+
+	// 超过重试次数
+	server, count = collector(t, 10, http.StatusTooManyRequests, body)
+	defer server.Close()
+
+	option.URL = server.URL
+	option.Retry = 1
+	assert.Error(t, NewZipkinExporter(option).Export(context.Background(), testSpans()))
+	assert.Equal(t, int32(2), atomic.LoadInt32(count))
+}
+
+func TestExporterBatch(t *testing.T) {
+	var body = make(chan []byte, 10)
+	server, _ := collector(t, 0, 0, body)
+	defer server.Close()
+
+	var (
+		exporter  = NewZipkinExporter(HTTPOption{URL: server.URL, Headers: map[string]string{"Authorization": "token"}})
+		processor = NewBatchProcessor(exporter, BatchOption{BatchSize: 2, Interval: time.Hour})
+	)
+
+	for _, span := range testSpans() {
+		processor.OnEnd(span)
+	}
+	assert.NoError(t, processor.Shutdown(context.Background()))
+
+	var total int
+	for len(body) > 0 {
+		var spans []zipkinSpan
+		assert.NoError(t, json.Unmarshal(<-body, &spans))
+		assert.True(t, len(spans) <= 2)
+		total += len(spans)
+	}
+	assert.Equal(t, 3, total)
+}
+
+func TestFileExporter(t *testing.T) {
+	var (
+		buf      bytes.Buffer
+		exporter = NewFileExporter(&buf)
+	)
+
+	assert.NoError(t, exporter.Export(context.Background(), testSpans()))
+	assert.NoError(t, exporter.Shutdown(context.Background()))
+
+	var (
+		scanner = bufio.NewScanner(&buf)
+		names   []string
+	)
+	for scanner.Scan() {
+		var span SpanData
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &span))
+		names = append(names, span.Name)
+	}
+	assert.Equal(t, []string{"GET /user", "redis", "GET /order"}, names)
+}
@@ -0,0 +1,116 @@
+package trace
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// Transport 客户端RoundTripper, 为携带trace的请求创建客户端span并写入请求头
+type Transport struct {
+	Base http.RoundTripper // 为nil时使用http.DefaultTransport
+}
+
+// 记录状态码的ResponseWriter
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+// Handler net/http服务端中间件, 读取请求头中的trace并创建服务端span, 下游通过Get(r.Context())获取;
+// 包装gin.Engine时需开启ContextWithFallback, gin路由建议直接使用micro.Trace
+func Handler(callee string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ctx = Set(r.Context(), New(r, callee))
+
+		// 未知路由模板, 以方法命名避免高基数
+		ctx, span := StartSpan(ctx, r.Method, WithKind(KindServer), WithAttributes(map[string]interface{}{
+			"http.method": r.Method,
+			"http.target": r.URL.Path,
+		}))
+
+		var writer = &statusWriter{ResponseWriter: w, code: http.StatusOK}
+
+		defer func() {
+			if e := recover(); e != nil {
+				span.SetStatus(StatusError, fmt.Sprint(e))
+				span.End()
+				panic(e)
+			}
+		}()
+
+		next.ServeHTTP(writer, r.WithContext(ctx))
+
+		EndHTTPSpan(span, writer.code)
+	})
+}
+
+// NewTransport 创建客户端RoundTripper
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// RoundTrip 请求ctx中没有trace时直接转发; span在收到响应头时结束
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var base = t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if Get(req.Context()) == nil {
+		return base.RoundTrip(req)
+	}
+
+	ctx, span := StartSpan(req.Context(), "HTTP "+req.Method, WithKind(KindClient), WithAttributes(map[string]interface{}{
+		"http.method": req.Method,
+		"http.url":    req.URL.String(),
+	}))
+
+	// RoundTripper不能修改原请求
+	req = req.Clone(ctx)
+	span.Trace().SetHeader(req.Header)
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+
+	EndHTTPSpan(span, resp.StatusCode)
+
+	return resp, nil
+}
+
+// EndHTTPSpan 记录HTTP状态码并结束span, 5xx为错误
+func EndHTTPSpan(span *Span, code int) {
+	span.SetAttribute("http.status_code", code)
+	if code >= http.StatusInternalServerError {
+		span.SetStatus(StatusError, http.StatusText(code))
+	}
+	span.End()
+}
+
+func (s *statusWriter) WriteHeader(code int) {
+	s.code = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusWriter) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (s *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := s.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("trace: response writer does not implement http.Hijacker")
+}
+
+func (s *statusWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
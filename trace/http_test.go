@@ -0,0 +1,106 @@
+package trace
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Transport(t *testing.T) {
+	var exporter = NewMemoryExporter()
+	RegisterProcessor(NewSimpleProcessor(exporter))
+	defer Shutdown(context.Background())
+
+	var server = httptest.NewServer(Handler("server", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var trace = Get(r.Context())
+		if r.Header.Get("traceparent") != "" {
+			assert.Equal(t, "client", trace.Caller)
+		}
+		assert.Equal(t, "server", trace.Callee)
+		assert.NotNil(t, SpanFromContext(r.Context()))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})))
+	defer server.Close()
+
+	var (
+		client = &http.Client{Transport: NewTransport(nil)}
+		parent = New(nil, "client")
+		ctx    = Set(context.Background(), parent)
+	)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/user/1", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	// 不修改原请求
+	assert.Empty(t, req.Header)
+
+	var spans = exporter.Spans()
+	if assert.Len(t, spans, 2) {
+		var server, client = spans[0], spans[1]
+		assert.Equal(t, KindServer, server.Kind)
+		assert.Equal(t, "GET", server.Name)
+		assert.Equal(t, "/user/1", server.Attributes["http.target"])
+		assert.Equal(t, StatusError, server.Status)
+		assert.Equal(t, KindClient, client.Kind)
+		assert.Equal(t, 503, client.Attributes["http.status_code"])
+		assert.Equal(t, parent.TraceID, server.TraceID)
+		assert.Equal(t, client.SpanID, server.ParentID)
+	}
+
+	// 没有trace时不创建span
+	exporter.Reset()
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Len(t, exporter.Spans(), 1)
+}
+
+func TestStore(t *testing.T) {
+	var (
+		ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+		trace  = New(nil, "test")
+	)
+
+	// gin.Context原地设置
+	assert.Equal(t, ctx, Set(ctx, trace))
+	assert.Equal(t, trace, Get(ctx))
+	value, _ := ctx.Get(contextKey)
+	assert.Equal(t, trace, value)
+
+	// 由gin.Context派生的ctx(不再是Store)仍可读取原地设置的trace和span
+	_, span := StartSpan(context.Background(), "gin")
+	SetSpan(ctx, span)
+	timeout, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	assert.NotNil(t, Get(timeout))
+	assert.Same(t, Get(ctx), Get(timeout))
+	assert.Equal(t, span, SpanFromContext(timeout))
+
+	// 未原地设置时读取请求ctx(gin需开启ContextWithFallback)
+	ctx, engine := gin.CreateTestContext(httptest.NewRecorder())
+	engine.ContextWithFallback = true
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	ctx.Request = ctx.Request.WithContext(Set(ctx.Request.Context(), trace))
+	assert.Equal(t, trace, Get(ctx))
+
+	// 派生ctx读取派生的trace
+	derived, span := StartSpan(ctx, "child")
+	assert.Equal(t, span.Trace(), Get(derived))
+	assert.Equal(t, trace, Get(ctx))
+
+	assert.Nil(t, Get(context.Background()))
+	assert.Nil(t, SpanFromContext(context.Background()))
+}
@@ -0,0 +1,137 @@
+package trace
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand"
+	"sync"
+
+	"github.com/zooyer/miskit/zuid"
+)
+
+// IDGenerator trace/span id生成器
+type IDGenerator interface {
+	// TraceID 16字节trace id(32位小写十六进制, 不全为0)
+	TraceID() string
+	// SpanID 8字节span id(16位小写十六进制, 不全为0)
+	SpanID() string
+}
+
+// 随机生成器, crypto/rand生成种子, math/rand快速生成
+type randomIDGenerator struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+// zuid生成器, 高8字节为有序的zuid, trace id低8字节随机(用于按比例采样)
+type zuidIDGenerator struct {
+	node   *zuid.Node
+	random *randomIDGenerator
+}
+
+var (
+	generatorMutex   sync.RWMutex
+	defaultGenerator             = newRandomIDGenerator()
+	generator        IDGenerator = defaultGenerator
+)
+
+// SetIDGenerator 设置id生成器, 默认NewRandomIDGenerator()
+func SetIDGenerator(g IDGenerator) {
+	generatorMutex.Lock()
+	defer generatorMutex.Unlock()
+
+	generator = g
+}
+
+// GetIDGenerator 当前id生成器
+func GetIDGenerator() IDGenerator {
+	generatorMutex.RLock()
+	defer generatorMutex.RUnlock()
+
+	return generator
+}
+
+// NewRandomIDGenerator 创建随机id生成器, 种子来自crypto/rand, 避免多实例种子相同导致冲突
+func NewRandomIDGenerator() IDGenerator {
+	return newRandomIDGenerator()
+}
+
+// NewZUIDGenerator 创建zuid id生成器, node为nil时使用zuid.Snowflake(0, 0)
+func NewZUIDGenerator(node *zuid.Node) IDGenerator {
+	if node == nil {
+		node = zuid.Snowflake(0, 0)
+	}
+
+	return &zuidIDGenerator{
+		node:   node,
+		random: newRandomIDGenerator(),
+	}
+}
+
+func newRandomIDGenerator() *randomIDGenerator {
+	var seed int64
+	if err := binary.Read(crand.Reader, binary.LittleEndian, &seed); err != nil {
+		panic(err)
+	}
+
+	return &randomIDGenerator{
+		rand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// 读取非全0的随机字节
+func (r *randomIDGenerator) read(data []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for {
+		r.rand.Read(data)
+		for _, b := range data {
+			if b != 0 {
+				return
+			}
+		}
+	}
+}
+
+func (r *randomIDGenerator) TraceID() string {
+	var id [16]byte
+	r.read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+func (r *randomIDGenerator) SpanID() string {
+	var id [8]byte
+	r.read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+func (z *zuidIDGenerator) TraceID() string {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], uint64(z.node.GenID()))
+	z.random.read(id[8:])
+	return hex.EncodeToString(id[:])
+}
+
+func (z *zuidIDGenerator) SpanID() string {
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], uint64(z.node.GenID()))
+	return hex.EncodeToString(id[:])
+}
+
+// genTraceID 生成trace id, 自定义生成器结果无效时使用默认生成器
+func genTraceID() string {
+	if id := GetIDGenerator().TraceID(); validID(id, 32) {
+		return id
+	}
+	return defaultGenerator.TraceID()
+}
+
+// genSpanID 生成span id
+func genSpanID() string {
+	if id := GetIDGenerator().SpanID(); validID(id, 16) {
+		return id
+	}
+	return defaultGenerator.SpanID()
+}
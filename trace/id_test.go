@@ -0,0 +1,75 @@
+package trace
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zooyer/miskit/zuid"
+)
+
+type invalidIDGenerator struct{}
+
+func (invalidIDGenerator) TraceID() string { return "00000000000000000000000000000000" }
+
+func (invalidIDGenerator) SpanID() string { return "invalid" }
+
+func TestRandomIDGenerator(t *testing.T) {
+	var (
+		mutex     sync.Mutex
+		wg        sync.WaitGroup
+		generator = NewRandomIDGenerator()
+		ids       = make(map[string]bool)
+	)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				var traceID, spanID = generator.TraceID(), generator.SpanID()
+				assert.True(t, validID(traceID, 32), traceID)
+				assert.True(t, validID(spanID, 16), spanID)
+
+				mutex.Lock()
+				assert.False(t, ids[traceID])
+				ids[traceID] = true
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 不同实例种子不同
+	assert.NotEqual(t, NewRandomIDGenerator().TraceID(), NewRandomIDGenerator().TraceID())
+}
+
+func TestZUIDGenerator(t *testing.T) {
+	var generator = NewZUIDGenerator(zuid.Snowflake(0, 1))
+
+	var last string
+	for i := 0; i < 100; i++ {
+		var traceID, spanID = generator.TraceID(), generator.SpanID()
+		assert.True(t, validID(traceID, 32), traceID)
+		assert.True(t, validID(spanID, 16), spanID)
+		// 高8字节有序
+		assert.True(t, traceID[:16] > last)
+		last = traceID[:16]
+	}
+
+	assert.True(t, validID(NewZUIDGenerator(nil).SpanID(), 16))
+}
+
+func TestSetIDGenerator(t *testing.T) {
+	defer SetIDGenerator(GetIDGenerator())
+
+	SetIDGenerator(NewZUIDGenerator(nil))
+	var trace = New(nil, "test")
+	assert.True(t, validID(trace.TraceID, 32))
+	assert.True(t, validID(trace.GenChild().SpanID, 16))
+
+	// 无效id使用默认生成器
+	SetIDGenerator(invalidIDGenerator{})
+	assert.True(t, validID(genTraceID(), 32))
+	assert.True(t, validID(genSpanID(), 16))
+}
@@ -0,0 +1,196 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OTLP/HTTP JSON编码, 参考opentelemetry-proto trace/v1
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	TraceState        string         `json:"traceState,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+const otlpScopeName = "github.com/zooyer/miskit/trace"
+
+// NewOTLPExporter 创建OTLP/HTTP JSON导出器, URL一般为http://collector:4318/v1/traces
+func NewOTLPExporter(option HTTPOption) Exporter {
+	return newHTTPExporter(option, EncodeOTLP)
+}
+
+// EncodeOTLP 编码为OTLP/HTTP JSON请求体, 按服务名分组为resource
+func EncodeOTLP(spans []*SpanData) ([]byte, error) {
+	var (
+		request  otlpRequest
+		services = make(map[string]int)
+	)
+
+	for _, span := range spans {
+		index, exists := services[span.Service]
+		if !exists {
+			index = len(request.ResourceSpans)
+			services[span.Service] = index
+			request.ResourceSpans = append(request.ResourceSpans, otlpResourceSpans{
+				Resource: otlpResource{
+					Attributes: otlpAttributes(map[string]interface{}{"service.name": span.Service}),
+				},
+				ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: otlpScopeName}}},
+			})
+		}
+
+		var scope = &request.ResourceSpans[index].ScopeSpans[0]
+		scope.Spans = append(scope.Spans, otlpFromSpan(span))
+	}
+
+	return json.Marshal(request)
+}
+
+func otlpFromSpan(span *SpanData) otlpSpan {
+	var s = otlpSpan{
+		TraceID:           W3CTraceID(span.TraceID),
+		SpanID:            W3CSpanID(span.SpanID),
+		Name:              span.Name,
+		Kind:              int(span.Kind) + 1, // SPAN_KIND_UNSPECIFIED为0
+		StartTimeUnixNano: otlpTime(span.StartTime),
+		EndTimeUnixNano:   otlpTime(span.EndTime),
+		Attributes:        otlpAttributes(span.Attributes),
+		Status: otlpStatus{
+			Code:    int(span.Status),
+			Message: span.StatusMessage,
+		},
+	}
+
+	if span.ParentID != "" {
+		s.ParentSpanID = W3CSpanID(span.ParentID)
+	}
+
+	for _, event := range span.Events {
+		s.Events = append(s.Events, otlpEvent{
+			TimeUnixNano: otlpTime(event.Time),
+			Name:         event.Name,
+			Attributes:   otlpAttributes(event.Attributes),
+		})
+	}
+
+	return s
+}
+
+// 按key排序保证输出稳定
+func otlpAttributes(attributes map[string]interface{}) []otlpKeyValue {
+	var keys = make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var list = make([]otlpKeyValue, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, otlpKeyValue{Key: key, Value: otlpValue(attributes[key])})
+	}
+
+	return list
+}
+
+func otlpValue(value interface{}) otlpAnyValue {
+	var val otlpAnyValue
+
+	switch v := value.(type) {
+	case string:
+		val.StringValue = &v
+	case bool:
+		val.BoolValue = &v
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		var s = fmt.Sprint(v)
+		val.IntValue = &s
+	case float32:
+		var f = float64(v)
+		val.DoubleValue = &f
+	case float64:
+		val.DoubleValue = &v
+	case []string:
+		val.ArrayValue = new(otlpArrayValue)
+		for _, s := range v {
+			val.ArrayValue.Values = append(val.ArrayValue.Values, otlpValue(s))
+		}
+	case []interface{}:
+		val.ArrayValue = new(otlpArrayValue)
+		for _, s := range v {
+			val.ArrayValue.Values = append(val.ArrayValue.Values, otlpValue(s))
+		}
+	default:
+		var s = fmt.Sprint(v)
+		val.StringValue = &s
+	}
+
+	return val
+}
+
+func otlpTime(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,296 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Exporter span导出器
+type Exporter interface {
+	Export(ctx context.Context, spans []*SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// Processor span处理器, span结束时调用
+type Processor interface {
+	OnEnd(span *SpanData)
+	ForceFlush(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
+// BatchOption 批量处理选项, 零值使用默认值
+type BatchOption struct {
+	QueueSize int           // 队列大小, 队列满时丢弃, 默认2048
+	BatchSize int           // 单批最大数量, 默认512
+	Interval  time.Duration // 导出间隔, 默认5s
+	Timeout   time.Duration // 单次导出超时, 默认30s
+}
+
+// BatchProcessor 批量处理器, 异步批量导出
+type BatchProcessor struct {
+	option   BatchOption
+	exporter Exporter
+	queue    chan *SpanData
+	flush    chan chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+	dropped  int64
+	failed   int64
+}
+
+// SimpleProcessor 同步处理器, span结束时立即导出(用于测试和调试)
+type SimpleProcessor struct {
+	exporter Exporter
+	failed   int64
+}
+
+// MemoryExporter 内存导出器(用于测试)
+type MemoryExporter struct {
+	mutex sync.Mutex
+	spans []*SpanData
+}
+
+var (
+	processorMutex sync.RWMutex
+	processors     []Processor
+)
+
+// RegisterProcessor 注册span处理器
+func RegisterProcessor(processor Processor) {
+	processorMutex.Lock()
+	defer processorMutex.Unlock()
+
+	processors = append(processors, processor)
+}
+
+// Processors 已注册的处理器
+func Processors() []Processor {
+	processorMutex.RLock()
+	defer processorMutex.RUnlock()
+
+	return processors
+}
+
+// Shutdown 关闭并移除所有处理器(导出剩余span)
+func Shutdown(ctx context.Context) (err error) {
+	processorMutex.Lock()
+	var list = processors
+	processors = nil
+	processorMutex.Unlock()
+
+	for _, processor := range list {
+		if e := processor.Shutdown(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return
+}
+
+// NewBatchProcessor 创建批量处理器
+func NewBatchProcessor(exporter Exporter, option BatchOption) *BatchProcessor {
+	if option.QueueSize <= 0 {
+		option.QueueSize = 2048
+	}
+	if option.BatchSize <= 0 {
+		option.BatchSize = 512
+	}
+	if option.BatchSize > option.QueueSize {
+		option.BatchSize = option.QueueSize
+	}
+	if option.Interval <= 0 {
+		option.Interval = 5 * time.Second
+	}
+	if option.Timeout <= 0 {
+		option.Timeout = 30 * time.Second
+	}
+
+	var b = &BatchProcessor{
+		option:   option,
+		exporter: exporter,
+		queue:    make(chan *SpanData, option.QueueSize),
+		flush:    make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go b.run()
+
+	return b
+}
+
+func (b *BatchProcessor) run() {
+	var (
+		ticker = time.NewTicker(b.option.Interval)
+		batch  = make([]*SpanData, 0, b.option.BatchSize)
+	)
+	defer ticker.Stop()
+	defer close(b.done)
+
+	var export = func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), b.option.Timeout)
+		if err := b.exporter.Export(ctx, batch); err != nil {
+			atomic.AddInt64(&b.failed, int64(len(batch)))
+		}
+		cancel()
+
+		batch = make([]*SpanData, 0, b.option.BatchSize)
+	}
+
+	var drain = func() {
+		for {
+			select {
+			case span := <-b.queue:
+				if batch = append(batch, span); len(batch) >= b.option.BatchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case span := <-b.queue:
+			if batch = append(batch, span); len(batch) >= b.option.BatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case ch := <-b.flush:
+			drain()
+			close(ch)
+		case <-b.stop:
+			drain()
+			return
+		}
+	}
+}
+
+// OnEnd 加入队列, 队列满或已关闭时丢弃
+func (b *BatchProcessor) OnEnd(span *SpanData) {
+	select {
+	case <-b.stop:
+		atomic.AddInt64(&b.dropped, 1)
+		return
+	default:
+	}
+
+	select {
+	case b.queue <- span:
+	default:
+		atomic.AddInt64(&b.dropped, 1)
+	}
+}
+
+// Dropped 丢弃的span数量
+func (b *BatchProcessor) Dropped() int64 {
+	return atomic.LoadInt64(&b.dropped)
+}
+
+// Failed 导出失败的span数量
+func (b *BatchProcessor) Failed() int64 {
+	return atomic.LoadInt64(&b.failed)
+}
+
+// ForceFlush 立即导出队列中的span
+func (b *BatchProcessor) ForceFlush(ctx context.Context) error {
+	var ch = make(chan struct{})
+
+	select {
+	case b.flush <- ch:
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown 导出剩余span并关闭导出器
+func (b *BatchProcessor) Shutdown(ctx context.Context) error {
+	b.once.Do(func() {
+		close(b.stop)
+	})
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return b.exporter.Shutdown(ctx)
+}
+
+// NewSimpleProcessor 创建同步处理器
+func NewSimpleProcessor(exporter Exporter) *SimpleProcessor {
+	return &SimpleProcessor{exporter: exporter}
+}
+
+func (s *SimpleProcessor) OnEnd(span *SpanData) {
+	if err := s.exporter.Export(context.Background(), []*SpanData{span}); err != nil {
+		atomic.AddInt64(&s.failed, 1)
+	}
+}
+
+// Failed 导出失败的span数量
+func (s *SimpleProcessor) Failed() int64 {
+	return atomic.LoadInt64(&s.failed)
+}
+
+func (s *SimpleProcessor) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
+func (s *SimpleProcessor) Shutdown(ctx context.Context) error {
+	return s.exporter.Shutdown(ctx)
+}
+
+// NewMemoryExporter 创建内存导出器
+func NewMemoryExporter() *MemoryExporter {
+	return new(MemoryExporter)
+}
+
+func (m *MemoryExporter) Export(ctx context.Context, spans []*SpanData) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.spans = append(m.spans, spans...)
+
+	return nil
+}
+
+func (m *MemoryExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Spans 已导出的span
+func (m *MemoryExporter) Spans() []*SpanData {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]*SpanData(nil), m.spans...)
+}
+
+// Reset 清空
+func (m *MemoryExporter) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.spans = nil
+}
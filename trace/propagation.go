@@ -0,0 +1,361 @@
+package trace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Carrier trace载体(HTTP请求头、消息头、环境变量等)
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// HeaderCarrier HTTP请求头载体
+type HeaderCarrier http.Header
+
+// MetadataCarrier gRPC风格元数据载体, key统一小写, 可多值
+type MetadataCarrier map[string][]string
+
+// Propagator 跨进程传递trace
+type Propagator interface {
+	// Inject 写入载体
+	Inject(trace *Trace, carrier Carrier)
+	// Extract 从载体读取, 未读取到trace时返回false
+	Extract(carrier Carrier, trace *Trace) bool
+}
+
+// ZPropagator Z-*请求头
+type ZPropagator struct{}
+
+// W3CPropagator W3C Trace Context(traceparent/tracestate)
+type W3CPropagator struct{}
+
+// B3Propagator Zipkin B3, Single为true时使用单请求头b3, 否则使用X-B3-*多请求头
+type B3Propagator struct {
+	Single bool
+}
+
+const (
+	w3cHeaderTraceParent = "traceparent"
+	w3cHeaderTraceState  = "tracestate"
+	w3cVersion           = "00"
+	w3cMaxStateMembers   = 32
+
+	b3HeaderSingle   = "b3"
+	b3HeaderTraceID  = "X-B3-TraceId"
+	b3HeaderSpanID   = "X-B3-SpanId"
+	b3HeaderParentID = "X-B3-ParentSpanId"
+	b3HeaderSampled  = "X-B3-Sampled"
+	b3HeaderFlags    = "X-B3-Flags"
+)
+
+// FlagSampled W3C trace-flags采样标记
+const FlagSampled byte = 0x01
+
+var (
+	propagatorMutex sync.RWMutex
+	propagators     = []Propagator{ZPropagator{}, W3CPropagator{}}
+)
+
+func (h HeaderCarrier) Get(key string) string {
+	return http.Header(h).Get(key)
+}
+
+func (h HeaderCarrier) Set(key, value string) {
+	http.Header(h).Set(key, value)
+}
+
+func (m MetadataCarrier) Get(key string) string {
+	if values := m[strings.ToLower(key)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (m MetadataCarrier) Set(key, value string) {
+	m[strings.ToLower(key)] = []string{value}
+}
+
+// SetPropagators 设置传递方式, 读取时按顺序使用第一个读取到的, 写入时全部写入; 默认Z-*和W3C
+func SetPropagators(p ...Propagator) {
+	propagatorMutex.Lock()
+	defer propagatorMutex.Unlock()
+
+	propagators = p
+}
+
+// Propagators 当前传递方式
+func Propagators() []Propagator {
+	propagatorMutex.RLock()
+	defer propagatorMutex.RUnlock()
+
+	return propagators
+}
+
+// Inject 使用全部传递方式写入载体
+func (t *Trace) Inject(carrier Carrier) {
+	if t == nil {
+		return
+	}
+
+	var trace = *t
+	if trace.TraceID == "" {
+		trace.TraceID = genTraceID()
+	}
+	if trace.SpanID == "" {
+		trace.SpanID = genSpanID()
+	}
+
+	for _, p := range Propagators() {
+		p.Inject(&trace, carrier)
+	}
+
+	injectBaggage(&trace, carrier)
+}
+
+// extract 按顺序读取载体, baggage独立于trace上下文读取
+func (t *Trace) extract(carrier Carrier) bool {
+	extractBaggage(carrier, t)
+
+	for _, p := range Propagators() {
+		if p.Extract(carrier, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ZPropagator) Inject(t *Trace, carrier Carrier) {
+	carrier.Set(httpHeaderKeyTraceID, t.TraceID)
+	carrier.Set(httpHeaderKeySpanID, t.SpanID)
+	if t.Flags&FlagSampled != 0 {
+		carrier.Set(httpHeaderKeySampled, "1")
+	} else {
+		carrier.Set(httpHeaderKeySampled, "0")
+	}
+	if t.Lang != "" {
+		carrier.Set(httpHeaderKeyLang, t.Lang)
+	}
+	if t.Tag != "" {
+		carrier.Set(httpHeaderKeyTag, t.Tag)
+	}
+	if t.Callee != "" {
+		carrier.Set(httpHeaderKeyCaller, t.Callee)
+	}
+	if len(t.Content) > 0 {
+		carrier.Set(httpHeaderKeyContent, string(t.Content))
+	}
+}
+
+func (ZPropagator) Extract(carrier Carrier, t *Trace) bool {
+	t.Caller = carrier.Get(httpHeaderKeyCaller)
+	t.Lang = carrier.Get(httpHeaderKeyLang)
+	t.Tag = carrier.Get(httpHeaderKeyTag)
+	if content := carrier.Get(httpHeaderKeyContent); content != "" {
+		t.Content = []byte(content)
+	}
+
+	var traceID = carrier.Get(httpHeaderKeyTraceID)
+	if traceID == "" {
+		return false
+	}
+
+	t.TraceID = traceID
+	t.SpanID = carrier.Get(httpHeaderKeySpanID)
+	// 未携带采样标记的上游视为已采样
+	t.Flags = FlagSampled
+	if carrier.Get(httpHeaderKeySampled) == "0" {
+		t.Flags = 0
+	}
+
+	return true
+}
+
+func (W3CPropagator) Inject(t *Trace, carrier Carrier) {
+	carrier.Set(w3cHeaderTraceParent, fmt.Sprintf("%s-%s-%s-%02x", w3cVersion, W3CTraceID(t.TraceID), W3CSpanID(t.SpanID), t.Flags))
+	if t.State != "" {
+		carrier.Set(w3cHeaderTraceState, t.State)
+	}
+}
+
+func (W3CPropagator) Extract(carrier Carrier, t *Trace) bool {
+	var parts = strings.Split(strings.TrimSpace(carrier.Get(w3cHeaderTraceParent)), "-")
+	if len(parts) < 4 {
+		return false
+	}
+
+	var (
+		version  = parts[0]
+		traceID  = parts[1]
+		parentID = parts[2]
+		flags    = parts[3]
+	)
+
+	// 版本00必须恰好4段, 未知版本向前兼容
+	if len(version) != 2 || !isHex(version) || version == "ff" || version == w3cVersion && len(parts) != 4 {
+		return false
+	}
+	if !validID(traceID, 32) || !validID(parentID, 16) || len(flags) != 2 || !isHex(flags) {
+		return false
+	}
+
+	data, _ := hex.DecodeString(flags)
+
+	t.TraceID = traceID
+	t.SpanID = parentID
+	t.Flags = data[0]
+	t.State = parseTraceState(carrier.Get(w3cHeaderTraceState))
+
+	return true
+}
+
+func (b B3Propagator) Inject(t *Trace, carrier Carrier) {
+	var sampled = "0"
+	if t.Flags&FlagSampled != 0 {
+		sampled = "1"
+	}
+
+	if b.Single {
+		var value = fmt.Sprintf("%s-%s-%s", W3CTraceID(t.TraceID), W3CSpanID(t.SpanID), sampled)
+		if t.ParentID != "" {
+			value += "-" + W3CSpanID(t.ParentID)
+		}
+		carrier.Set(b3HeaderSingle, value)
+		return
+	}
+
+	carrier.Set(b3HeaderTraceID, W3CTraceID(t.TraceID))
+	carrier.Set(b3HeaderSpanID, W3CSpanID(t.SpanID))
+	if t.ParentID != "" {
+		carrier.Set(b3HeaderParentID, W3CSpanID(t.ParentID))
+	}
+	carrier.Set(b3HeaderSampled, sampled)
+}
+
+func (b B3Propagator) Extract(carrier Carrier, t *Trace) bool {
+	// 单请求头优先
+	if value := strings.ToLower(strings.TrimSpace(carrier.Get(b3HeaderSingle))); value != "" {
+		var parts = strings.Split(value, "-")
+		if len(parts) < 2 {
+			return false
+		}
+		if !validID(parts[0], 16) && !validID(parts[0], 32) || !validID(parts[1], 16) {
+			return false
+		}
+
+		t.TraceID = padID(parts[0], 32)
+		t.SpanID = parts[1]
+		t.Flags = FlagSampled
+		if len(parts) > 2 {
+			t.Flags = b3Flags(parts[2])
+		}
+		if len(parts) > 3 && validID(parts[3], 16) {
+			t.ParentID = parts[3]
+		}
+
+		return true
+	}
+
+	var (
+		traceID  = strings.ToLower(carrier.Get(b3HeaderTraceID))
+		spanID   = strings.ToLower(carrier.Get(b3HeaderSpanID))
+		parentID = strings.ToLower(carrier.Get(b3HeaderParentID))
+	)
+
+	if !validID(traceID, 16) && !validID(traceID, 32) || !validID(spanID, 16) {
+		return false
+	}
+
+	t.TraceID = padID(traceID, 32)
+	t.SpanID = spanID
+	t.Flags = FlagSampled
+	if validID(parentID, 16) {
+		t.ParentID = parentID
+	}
+	if sampled := carrier.Get(b3HeaderSampled); sampled != "" {
+		t.Flags = b3Flags(sampled)
+	}
+	if carrier.Get(b3HeaderFlags) == "1" {
+		t.Flags = FlagSampled
+	}
+
+	return true
+}
+
+// b3采样标记: 1/true/d(debug)为采样
+func b3Flags(sampled string) byte {
+	switch strings.ToLower(sampled) {
+	case "1", "true", "d":
+		return FlagSampled
+	}
+	return 0
+}
+
+// W3CTraceID 转换为W3C格式trace id(32位小写十六进制), 十六进制id左补0, 其他格式取sha256前16字节
+func W3CTraceID(id string) string {
+	return toHexID(id, 32)
+}
+
+// W3CSpanID 转换为W3C格式span id(16位小写十六进制), 十六进制id左补0, 其他格式取sha256前8字节
+func W3CSpanID(id string) string {
+	return toHexID(id, 16)
+}
+
+func toHexID(id string, size int) string {
+	var lower = strings.ToLower(id)
+	if len(lower) <= size && isHex(lower) && strings.Trim(lower, "0") != "" {
+		return padID(lower, size)
+	}
+
+	var sum = sha256.Sum256([]byte(id))
+
+	return hex.EncodeToString(sum[:size/2])
+}
+
+func padID(id string, size int) string {
+	if len(id) >= size {
+		return id
+	}
+	return strings.Repeat("0", size-len(id)) + id
+}
+
+// 校验十六进制id(长度为size且不全为0)
+func validID(id string, size int) bool {
+	return len(id) == size && isHex(id) && strings.Trim(id, "0") != ""
+}
+
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'f':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// 解析tracestate, 丢弃无效成员并限制成员数量
+func parseTraceState(state string) string {
+	var members []string
+	for _, member := range strings.Split(state, ",") {
+		if member = strings.TrimSpace(member); member == "" {
+			continue
+		}
+		if index := strings.IndexByte(member, '='); index <= 0 || index == len(member)-1 {
+			continue
+		}
+		if members = append(members, member); len(members) == w3cMaxStateMembers {
+			break
+		}
+	}
+	return strings.Join(members, ",")
+}
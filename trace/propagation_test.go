@@ -0,0 +1,125 @@
+package trace
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestW3CPropagator(t *testing.T) {
+	var header = make(http.Header)
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	header.Set("tracestate", "rojo=00f067aa0ba902b7, invalid ,congo=t61rcWkgMzE")
+
+	var trace = new(Trace)
+	assert.True(t, W3CPropagator{}.Extract(HeaderCarrier(header), trace))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", trace.SpanID)
+	assert.Equal(t, FlagSampled, trace.Flags)
+	assert.Equal(t, "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE", trace.State)
+
+	var out = make(http.Header)
+	W3CPropagator{}.Inject(trace, HeaderCarrier(out))
+	assert.Equal(t, header.Get("traceparent"), out.Get("traceparent"))
+	assert.Equal(t, trace.State, out.Get("tracestate"))
+
+	// 无效traceparent
+	for _, value := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		header.Set("traceparent", value)
+		assert.False(t, W3CPropagator{}.Extract(HeaderCarrier(header), new(Trace)), value)
+	}
+}
+
+func TestB3Propagator(t *testing.T) {
+	var header = make(http.Header)
+	header.Set("b3", "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90")
+
+	var trace = new(Trace)
+	assert.True(t, B3Propagator{}.Extract(HeaderCarrier(header), trace))
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", trace.TraceID)
+	assert.Equal(t, "e457b5a2e4d86bd1", trace.SpanID)
+	assert.Equal(t, "05e3ac9a4f6e3b90", trace.ParentID)
+	assert.Equal(t, FlagSampled, trace.Flags)
+
+	var out = make(http.Header)
+	B3Propagator{Single: true}.Inject(trace, HeaderCarrier(out))
+	assert.Equal(t, header.Get("b3"), out.Get("b3"))
+
+	out = make(http.Header)
+	B3Propagator{}.Inject(trace, HeaderCarrier(out))
+	assert.Equal(t, "80f198ee56343ba864fe8b2a57d3eff7", out.Get("X-B3-TraceId"))
+	assert.Equal(t, "e457b5a2e4d86bd1", out.Get("X-B3-SpanId"))
+	assert.Equal(t, "05e3ac9a4f6e3b90", out.Get("X-B3-ParentSpanId"))
+	assert.Equal(t, "1", out.Get("X-B3-Sampled"))
+
+	// 多请求头, 64位trace id
+	header = make(http.Header)
+	header.Set("X-B3-TraceId", "A3CE929D0E0E4736")
+	header.Set("X-B3-SpanId", "00f067aa0ba902b7")
+	header.Set("X-B3-Sampled", "0")
+
+	trace = new(Trace)
+	assert.True(t, B3Propagator{}.Extract(HeaderCarrier(header), trace))
+	assert.Equal(t, "0000000000000000a3ce929d0e0e4736", trace.TraceID)
+	assert.Equal(t, byte(0), trace.Flags)
+}
+
+func TestSetPropagators(t *testing.T) {
+	defer SetPropagators(Propagators()...)
+
+	SetPropagators(B3Propagator{Single: true}, W3CPropagator{})
+
+	var header = make(http.Header)
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header = header
+
+	var trace = New(req, "test")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceID)
+	assert.Equal(t, byte(0), trace.Flags)
+
+	var child = trace.GenChild()
+	assert.Equal(t, "00f067aa0ba902b7", child.ParentID)
+
+	var out = make(http.Header)
+	child.SetHeader(out)
+	assert.Equal(t, "", out.Get("Z-TraceID"))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736-"+W3CSpanID(child.SpanID)+"-0-00f067aa0ba902b7", out.Get("b3"))
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+W3CSpanID(child.SpanID)+"-00", out.Get("traceparent"))
+}
+
+func TestW3CTraceID(t *testing.T) {
+	var id = genTraceID()
+	assert.Equal(t, id, W3CTraceID(id))
+	assert.Equal(t, "000000000000000000000000000000ab", W3CTraceID("AB"))
+	assert.Len(t, W3CTraceID("not-hex-trace-id"), 32)
+	assert.Equal(t, W3CTraceID("not-hex-trace-id"), W3CTraceID("not-hex-trace-id"))
+	assert.Len(t, W3CSpanID("this is span id"), 16)
+	assert.Equal(t, "00000000000004d2", W3CSpanID("4d2"))
+}
+
+func TestMetadataCarrier(t *testing.T) {
+	var (
+		trace    = New(nil, "client")
+		metadata = make(MetadataCarrier)
+	)
+	trace.SpanID = "00f067aa0ba902b7"
+	trace.Inject(metadata)
+
+	assert.Equal(t, []string{trace.TraceID}, metadata["z-traceid"])
+	assert.Equal(t, trace.TraceID, metadata.Get("Z-TraceID"))
+
+	var server = Extract(metadata, "server")
+	assert.Equal(t, trace.TraceID, server.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", server.SpanID)
+	assert.Equal(t, "client", server.Caller)
+}
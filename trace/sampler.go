@@ -0,0 +1,178 @@
+package trace
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Sampler 头部采样器, 在trace创建时决定是否采样, 决定随请求头传递给下游
+type Sampler interface {
+	// ShouldSample 是否采样, parent为上游传递的trace, 根节点时为nil
+	ShouldSample(traceID string, parent *Trace) bool
+	// Description 描述
+	Description() string
+}
+
+type alwaysSampler struct{}
+
+type neverSampler struct{}
+
+type ratioSampler struct {
+	ratio float64
+	bound uint64
+}
+
+type parentSampler struct {
+	root Sampler
+}
+
+type rateSampler struct {
+	mutex  sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+var (
+	samplerMutex sync.RWMutex
+	sampler      Sampler = ParentBased(AlwaysSample())
+)
+
+// SetSampler 设置全局采样器, 默认ParentBased(AlwaysSample())
+func SetSampler(s Sampler) {
+	samplerMutex.Lock()
+	defer samplerMutex.Unlock()
+
+	sampler = s
+}
+
+// GetSampler 当前全局采样器
+func GetSampler() Sampler {
+	samplerMutex.RLock()
+	defer samplerMutex.RUnlock()
+
+	return sampler
+}
+
+// AlwaysSample 全部采样
+func AlwaysSample() Sampler {
+	return alwaysSampler{}
+}
+
+// NeverSample 全部不采样
+func NeverSample() Sampler {
+	return neverSampler{}
+}
+
+// TraceIDRatio 按trace id比例采样, 同一trace id在各服务的结果一致
+func TraceIDRatio(ratio float64) Sampler {
+	if ratio >= 1 {
+		return AlwaysSample()
+	}
+	if ratio <= 0 {
+		return NeverSample()
+	}
+
+	return &ratioSampler{
+		ratio: ratio,
+		bound: uint64(ratio * (1 << 63)),
+	}
+}
+
+// ParentBased 有上游时沿用上游的采样决定, 根节点使用root
+func ParentBased(root Sampler) Sampler {
+	return &parentSampler{root: root}
+}
+
+// RateLimited 每秒最多采样perSecond个根trace(令牌桶, 允许1秒的突发)
+func RateLimited(perSecond float64) Sampler {
+	if perSecond <= 0 {
+		return NeverSample()
+	}
+
+	return &rateSampler{
+		rate:   perSecond,
+		tokens: math.Max(perSecond, 1),
+		last:   time.Now(),
+	}
+}
+
+func (alwaysSampler) ShouldSample(traceID string, parent *Trace) bool {
+	return true
+}
+
+func (alwaysSampler) Description() string {
+	return "AlwaysSample"
+}
+
+func (neverSampler) ShouldSample(traceID string, parent *Trace) bool {
+	return false
+}
+
+func (neverSampler) Description() string {
+	return "NeverSample"
+}
+
+// 取W3C格式trace id的低8字节(随机部分)与比例上界比较
+func (r *ratioSampler) ShouldSample(traceID string, parent *Trace) bool {
+	var id = W3CTraceID(traceID)
+
+	value, err := strconv.ParseUint(id[16:], 16, 64)
+	if err != nil {
+		return false
+	}
+
+	return value>>1 < r.bound
+}
+
+func (r *ratioSampler) Description() string {
+	return fmt.Sprintf("TraceIDRatio{%g}", r.ratio)
+}
+
+func (p *parentSampler) ShouldSample(traceID string, parent *Trace) bool {
+	if parent != nil {
+		return parent.Flags&FlagSampled != 0
+	}
+	return p.root.ShouldSample(traceID, parent)
+}
+
+func (p *parentSampler) Description() string {
+	return fmt.Sprintf("ParentBased{root:%s}", p.root.Description())
+}
+
+func (r *rateSampler) ShouldSample(traceID string, parent *Trace) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var now = time.Now()
+	r.tokens = math.Min(r.tokens+now.Sub(r.last).Seconds()*r.rate, math.Max(r.rate, 1))
+	r.last = now
+
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+
+	return true
+}
+
+func (r *rateSampler) Description() string {
+	return fmt.Sprintf("RateLimited{%g}", r.rate)
+}
+
+// sample 使用全局采样器设置采样标记
+func (t *Trace) sample(parent *Trace) {
+	if GetSampler().ShouldSample(t.TraceID, parent) {
+		t.Flags |= FlagSampled
+	} else {
+		t.Flags &^= FlagSampled
+	}
+}
+
+// Sampled 是否采样
+func (t *Trace) Sampled() bool {
+	return t != nil && t.Flags&FlagSampled != 0
+}
@@ -0,0 +1,89 @@
+package trace
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceIDRatio(t *testing.T) {
+	var (
+		sampler = TraceIDRatio(0.5)
+		sampled int
+	)
+
+	for i := 0; i < 1000; i++ {
+		var traceID = genTraceID()
+		var result = sampler.ShouldSample(traceID, nil)
+		// 同一trace id结果一致
+		assert.Equal(t, result, sampler.ShouldSample(traceID, nil))
+		if result {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 500, sampled, 150)
+
+	assert.True(t, TraceIDRatio(0.5).ShouldSample("00000000000000000000000000000001", nil))
+	assert.False(t, TraceIDRatio(0.5).ShouldSample("0000000000000000ffffffffffffffff", nil))
+	assert.Equal(t, "AlwaysSample", TraceIDRatio(1).Description())
+	assert.Equal(t, "NeverSample", TraceIDRatio(0).Description())
+}
+
+func TestParentBased(t *testing.T) {
+	var sampler = ParentBased(NeverSample())
+
+	assert.False(t, sampler.ShouldSample(genTraceID(), nil))
+	assert.True(t, sampler.ShouldSample(genTraceID(), &Trace{Flags: FlagSampled}))
+	assert.False(t, ParentBased(AlwaysSample()).ShouldSample(genTraceID(), &Trace{}))
+	assert.Equal(t, "ParentBased{root:NeverSample}", sampler.Description())
+}
+
+func TestRateLimited(t *testing.T) {
+	var (
+		sampler = RateLimited(10)
+		sampled int
+	)
+
+	for i := 0; i < 100; i++ {
+		if sampler.ShouldSample(genTraceID(), nil) {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 10, sampled, 1)
+	assert.False(t, RateLimited(0).ShouldSample(genTraceID(), nil))
+}
+
+func TestNew_Sampling(t *testing.T) {
+	defer SetSampler(GetSampler())
+	SetSampler(ParentBased(NeverSample()))
+
+	// 根节点使用root采样器
+	assert.False(t, New(nil, "test").Sampled())
+
+	// 沿用上游的采样决定
+	var req, _ = http.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	assert.True(t, New(req, "test").Sampled())
+
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00")
+	assert.False(t, New(req, "test").Sampled())
+
+	// 采样决定随请求头传递
+	SetSampler(AlwaysSample())
+	var trace = New(nil, "test")
+	var header = make(http.Header)
+	trace.SetHeader(header)
+	assert.Equal(t, "1", header.Get(httpHeaderKeySampled))
+
+	SetSampler(ParentBased(AlwaysSample()))
+	trace.Flags = 0
+	trace.SetHeader(header)
+	assert.Equal(t, "0", header.Get(httpHeaderKeySampled))
+	assert.False(t, New(&http.Request{Header: header}, "test").Sampled())
+
+	// 旧版本上游未携带采样标记视为已采样
+	header.Del(httpHeaderKeySampled)
+	header.Del("traceparent")
+	assert.True(t, New(&http.Request{Header: header}, "test").Sampled())
+}
@@ -0,0 +1,304 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SpanKind span类型
+type SpanKind int
+
+// Status span状态
+type Status int
+
+const (
+	KindInternal SpanKind = iota // 内部调用
+	KindServer                   // 服务端
+	KindClient                   // 客户端
+	KindProducer                 // 消息生产者
+	KindConsumer                 // 消息消费者
+)
+
+const (
+	StatusUnset Status = iota
+	StatusOK
+	StatusError
+)
+
+// Event span事件
+type Event struct {
+	Name       string                 `json:"name"`
+	Time       time.Time              `json:"time"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// SpanData 已结束span的快照, 用于导出
+type SpanData struct {
+	TraceID       string                 `json:"trace_id"`
+	SpanID        string                 `json:"span_id"`
+	ParentID      string                 `json:"parent_id,omitempty"`
+	Name          string                 `json:"name"`
+	Service       string                 `json:"service,omitempty"`
+	Kind          SpanKind               `json:"kind"`
+	StartTime     time.Time              `json:"start_time"`
+	EndTime       time.Time              `json:"end_time"`
+	Status        Status                 `json:"status"`
+	StatusMessage string                 `json:"status_message,omitempty"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	Events        []Event                `json:"events,omitempty"`
+}
+
+// Span 一次调用的计时单元, 方法均可在nil上调用
+type Span struct {
+	mutex     sync.Mutex
+	data      SpanData
+	trace     *Trace
+	ended     bool
+	recording bool
+}
+
+// SpanOption span选项
+type SpanOption func(span *Span)
+
+func (k SpanKind) String() string {
+	switch k {
+	case KindServer:
+		return "server"
+	case KindClient:
+		return "client"
+	case KindProducer:
+		return "producer"
+	case KindConsumer:
+		return "consumer"
+	}
+	return "internal"
+}
+
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "ok"
+	case StatusError:
+		return "error"
+	}
+	return "unset"
+}
+
+// Duration 耗时
+func (d *SpanData) Duration() time.Duration {
+	return d.EndTime.Sub(d.StartTime)
+}
+
+// WithKind 设置span类型
+func WithKind(kind SpanKind) SpanOption {
+	return func(span *Span) {
+		span.data.Kind = kind
+	}
+}
+
+// WithAttributes 设置span属性
+func WithAttributes(attributes map[string]interface{}) SpanOption {
+	return func(span *Span) {
+		for key, value := range attributes {
+			span.data.Attributes[key] = value
+		}
+	}
+}
+
+// WithStartTime 设置开始时间
+func WithStartTime(start time.Time) SpanOption {
+	return func(span *Span) {
+		span.data.StartTime = start
+	}
+}
+
+// StartSpan 创建子span, ctx中的span或trace作为父节点(没有时创建新trace), 返回携带子span的ctx
+func StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	var span = newSpan(Get(ctx), name, opts...)
+
+	ctx = context.WithValue(ctx, traceKey, span.trace)
+	ctx = context.WithValue(ctx, spanKey, span)
+
+	return ctx, span
+}
+
+func newSpan(parent *Trace, name string, opts ...SpanOption) *Span {
+	var trace *Trace
+	if parent != nil {
+		trace = parent.GenChild()
+	} else {
+		trace = New(nil, "")
+		trace.SpanID = genSpanID()
+	}
+
+	var span = &Span{
+		trace:     trace,
+		recording: trace.Flags&FlagSampled != 0,
+		data: SpanData{
+			TraceID:    trace.TraceID,
+			SpanID:     trace.SpanID,
+			ParentID:   trace.ParentID,
+			Name:       name,
+			Service:    trace.Callee,
+			StartTime:  time.Now(),
+			Attributes: make(map[string]interface{}),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(span)
+	}
+
+	return span
+}
+
+// SetSpan 设置span及其trace到ctx(Store原地设置)
+func SetSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+
+	ctx = setValue(ctx, spanKey, spanContextKey, span)
+
+	return Set(ctx, span.trace)
+}
+
+// SpanFromContext 获取ctx中的span
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := getValue(ctx, spanKey, spanContextKey).(*Span)
+	return span
+}
+
+// Trace span对应的trace(SpanID为当前span), 用于向下游传递
+func (s *Span) Trace() *Trace {
+	if s == nil {
+		return nil
+	}
+	return s.trace
+}
+
+// SpanID span id
+func (s *Span) SpanID() string {
+	if s == nil {
+		return ""
+	}
+	return s.data.SpanID
+}
+
+// IsRecording 是否记录(已采样且未结束)
+func (s *Span) IsRecording() bool {
+	if s == nil {
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.recording && !s.ended
+}
+
+// SetName 修改名称
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.ended {
+		s.data.Name = name
+	}
+}
+
+// SetAttribute 设置属性
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.ended {
+		s.data.Attributes[key] = value
+	}
+}
+
+// AddEvent 添加事件
+func (s *Span) AddEvent(name string, attributes map[string]interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.ended {
+		s.data.Events = append(s.data.Events, Event{
+			Name:       name,
+			Time:       time.Now(),
+			Attributes: attributes,
+		})
+	}
+}
+
+// SetStatus 设置状态
+func (s *Span) SetStatus(status Status, message string) {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.ended {
+		s.data.Status = status
+		s.data.StatusMessage = message
+	}
+}
+
+// RecordError 记录错误事件并设置错误状态
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.AddEvent("exception", map[string]interface{}{
+		"exception.message": err.Error(),
+	})
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End 结束span并交给处理器导出, 重复调用无效
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+
+	if !s.recording {
+		s.mutex.Unlock()
+		return
+	}
+
+	var data = s.data
+	data.Attributes = make(map[string]interface{}, len(s.data.Attributes))
+	for key, value := range s.data.Attributes {
+		data.Attributes[key] = value
+	}
+	data.Events = append([]Event(nil), s.data.Events...)
+	s.mutex.Unlock()
+
+	for _, processor := range Processors() {
+		processor.OnEnd(&data)
+	}
+}
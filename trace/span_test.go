@@ -0,0 +1,111 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartSpan(t *testing.T) {
+	var exporter = NewMemoryExporter()
+	RegisterProcessor(NewSimpleProcessor(exporter))
+	defer Shutdown(context.Background())
+
+	var ctx = Set(context.Background(), New(nil, "test"))
+
+	ctx, root := StartSpan(ctx, "root", WithKind(KindServer), WithAttributes(map[string]interface{}{"key": "value"}))
+	_, child := StartSpan(ctx, "child")
+	child.AddEvent("event", map[string]interface{}{"count": 1})
+	child.RecordError(errors.New("failed"))
+	child.End()
+	child.End()
+	root.SetStatus(StatusOK, "")
+	root.End()
+	root.SetAttribute("ignored", true)
+
+	var spans = exporter.Spans()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "child", spans[0].Name)
+		assert.Equal(t, "root", spans[1].Name)
+		assert.Equal(t, spans[1].SpanID, spans[0].ParentID)
+		assert.Equal(t, spans[1].TraceID, spans[0].TraceID)
+		assert.Equal(t, "test", spans[1].Service)
+		assert.Equal(t, KindServer, spans[1].Kind)
+		assert.Equal(t, map[string]interface{}{"key": "value"}, spans[1].Attributes)
+		assert.Equal(t, StatusError, spans[0].Status)
+		assert.Len(t, spans[0].Events, 2)
+		assert.True(t, spans[1].Duration() >= spans[0].Duration())
+	}
+
+	// 子span的trace用于向下游传递
+	assert.Equal(t, root.SpanID(), Get(ctx).SpanID)
+	assert.Equal(t, root, SpanFromContext(ctx))
+
+	// 未采样不导出
+	exporter.Reset()
+	var trace = New(nil, "test")
+	trace.Flags = 0
+	_, span := StartSpan(Set(context.Background(), trace), "unsampled")
+	assert.False(t, span.IsRecording())
+	span.End()
+	assert.Len(t, exporter.Spans(), 0)
+
+	// nil span
+	var nilSpan *Span
+	nilSpan.SetAttribute("key", "value")
+	nilSpan.End()
+}
+
+func TestBatchProcessor(t *testing.T) {
+	var (
+		exporter  = NewMemoryExporter()
+		processor = NewBatchProcessor(exporter, BatchOption{QueueSize: 4, BatchSize: 2, Interval: time.Hour})
+	)
+
+	for i := 0; i < 2; i++ {
+		processor.OnEnd(&SpanData{Name: "batch"})
+	}
+
+	// 满一批立即导出
+	assert.Eventually(t, func() bool { return len(exporter.Spans()) == 2 }, time.Second, time.Millisecond)
+
+	processor.OnEnd(&SpanData{Name: "flush"})
+	assert.NoError(t, processor.ForceFlush(context.Background()))
+	assert.Len(t, exporter.Spans(), 3)
+
+	processor.OnEnd(&SpanData{Name: "shutdown"})
+	assert.NoError(t, processor.Shutdown(context.Background()))
+	assert.Len(t, exporter.Spans(), 4)
+
+	processor.OnEnd(&SpanData{Name: "dropped"})
+	assert.Equal(t, int64(1), processor.Dropped())
+	assert.NoError(t, processor.ForceFlush(context.Background()))
+}
+
+type failExporter struct{}
+
+func (failExporter) Export(ctx context.Context, spans []*SpanData) error {
+	return errors.New("export failed")
+}
+
+func (failExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func TestProcessor_Failed(t *testing.T) {
+	var batch = NewBatchProcessor(failExporter{}, BatchOption{BatchSize: 2, Interval: time.Hour})
+	for i := 0; i < 3; i++ {
+		batch.OnEnd(&SpanData{Name: "failed"})
+	}
+	assert.NoError(t, batch.ForceFlush(context.Background()))
+	assert.Equal(t, int64(3), batch.Failed())
+	assert.Equal(t, int64(0), batch.Dropped())
+	assert.NoError(t, batch.Shutdown(context.Background()))
+
+	var simple = NewSimpleProcessor(failExporter{})
+	simple.OnEnd(&SpanData{Name: "failed"})
+	assert.Equal(t, int64(1), simple.Failed())
+}
@@ -1,31 +1,25 @@
 package trace
 
 import (
-	"bytes"
 	"context"
-	"encoding/hex"
 	"encoding/json"
-	"fmt"
-	"math/rand"
-	"net"
 	"net/http"
-	"os"
-	"sync"
-	"time"
-
-	"github.com/gin-gonic/gin"
 )
 
 type Trace struct {
-	Callee  string          `json:"callee,omitempty"`
-	Caller  string          `json:"caller,omitempty"`
-	TraceID string          `json:"trace_id,omitempty"`
-	SpanID  string          `json:"span_id,omitempty"`
-	ChildID string          `json:"child_id,omitempty"`
-	Lang    string          `json:"lang,omitempty"`
-	Tag     string          `json:"tag,omitempty"`
-	Content json.RawMessage `json:"content,omitempty"`
-	Request *http.Request   `json:"-"`
+	Callee   string            `json:"callee,omitempty"`
+	Caller   string            `json:"caller,omitempty"`
+	TraceID  string            `json:"trace_id,omitempty"`
+	SpanID   string            `json:"span_id,omitempty"`
+	ParentID string            `json:"parent_id,omitempty"`
+	ChildID  string            `json:"child_id,omitempty"`
+	Flags    byte              `json:"flags,omitempty"`
+	State    string            `json:"state,omitempty"`
+	Lang     string            `json:"lang,omitempty"`
+	Tag      string            `json:"tag,omitempty"`
+	Content  json.RawMessage   `json:"content,omitempty"`
+	Baggage  map[string]string `json:"baggage,omitempty"`
+	Request  *http.Request     `json:"-"`
 }
 
 const (
@@ -35,94 +29,58 @@ const (
 	httpHeaderKeyTag     = "Z-Tag"
 	httpHeaderKeyCaller  = "Z-Caller"
 	httpHeaderKeyContent = "Z-Content"
+	httpHeaderKeySampled = "Z-Sampled"
 )
 
-const (
-	contextKey = "z-context"
-)
-
-var (
-	pool = sync.Pool{
-		New: func() interface{} {
-			return &bytes.Buffer{}
-		},
+func New(req *http.Request, callee string) *Trace {
+	var carrier Carrier
+	if req != nil {
+		carrier = HeaderCarrier(req.Header)
 	}
-)
 
-func New(req *http.Request, callee string) *Trace {
+	var trace = Extract(carrier, callee)
+	trace.Request = req
+
+	return trace
+}
+
+// Extract 从载体(消息头、环境变量等)读取trace, 未读取到时创建新trace
+func Extract(carrier Carrier, callee string) *Trace {
 	var trace = new(Trace)
 
 	trace.Callee = callee
 
-	if req != nil {
-		trace.TraceID = req.Header.Get(httpHeaderKeyTraceID)
-		trace.Caller = req.Header.Get(httpHeaderKeyCaller)
-		trace.SpanID = req.Header.Get(httpHeaderKeySpanID)
-		trace.Lang = req.Header.Get(httpHeaderKeyLang)
-		trace.Tag = req.Header.Get(httpHeaderKeyTag)
-		trace.Content = []byte(req.Header.Get(httpHeaderKeyContent))
-		trace.Request = req
+	var parent *Trace
+	if carrier != nil && trace.extract(carrier) {
+		parent = trace.Clone()
 	}
 
 	if trace.TraceID == "" {
 		trace.TraceID = genTraceID()
 	}
 
+	trace.sample(parent)
+
 	return trace
 }
 
+// Set 设置trace到ctx, 返回携带trace的ctx(Store原地设置)
 func Set(ctx context.Context, trace *Trace) context.Context {
 	if trace == nil {
 		return ctx
 	}
-
-	switch ctx := ctx.(type) {
-	case *gin.Context:
-		ctx.Set(contextKey, trace)
-	default:
-		return context.WithValue(ctx, contextKey, trace)
-	}
-
-	return ctx
+	return setValue(ctx, traceKey, contextKey, trace)
 }
 
+// Get 获取ctx中的trace
 func Get(ctx context.Context) *Trace {
-	var trace *Trace
-	switch ctx := ctx.(type) {
-	case *gin.Context:
-		if value, exists := ctx.Get(contextKey); exists {
-			trace, _ = value.(*Trace)
-		}
-	default:
-		trace, _ = ctx.Value(contextKey).(*Trace)
-	}
+	trace, _ := getValue(ctx, traceKey, contextKey).(*Trace)
 	return trace
 }
 
 // SetHeader 设置到请求头
 func (t *Trace) SetHeader(header http.Header) {
-	if t == nil {
-		return
-	}
-
-	if t.TraceID != "" {
-		header.Set(httpHeaderKeyTraceID, t.TraceID)
-	} else {
-		header.Set(httpHeaderKeyTraceID, genTraceID())
-	}
-	header.Set(httpHeaderKeySpanID, genSpanID())
-	if t.Lang != "" {
-		header.Set(httpHeaderKeyLang, t.Lang)
-	}
-	if t.Tag != "" {
-		header.Set(httpHeaderKeyTag, t.Tag)
-	}
-	if t.Callee != "" {
-		header.Set(httpHeaderKeyCaller, t.Callee)
-	}
-	if len(t.Content) > 0 {
-		header.Set(httpHeaderKeyContent, string(t.Content))
-	}
+	t.Inject(HeaderCarrier(header))
 }
 
 // GenChild 生成子trace
@@ -136,6 +94,8 @@ func (t *Trace) GenChild() *Trace {
 	trace = *t
 	trace.Content = make(json.RawMessage, len(t.Content))
 	copy(trace.Content, t.Content)
+	trace.Baggage = t.cloneBaggage()
+	trace.ParentID = t.SpanID
 	trace.SpanID = genSpanID()
 
 	return &trace
@@ -152,54 +112,26 @@ func (t *Trace) Clone() *Trace {
 	trace = *t
 	trace.Content = make(json.RawMessage, len(t.Content))
 	copy(trace.Content, t.Content)
+	trace.Baggage = t.cloneBaggage()
 
 	return &trace
 }
 
-// String 序列化成字符串
-func (t *Trace) String() string {
-	data, _ := json.Marshal(t)
-	return string(data)
-}
-
-// genTraceID 生成trace id
-func genTraceID() string {
-	pid := os.Getegid()
-	now := time.Now()
-	unix := now.Unix()
-	nano := now.UnixNano()
-
-	var buf = pool.Get().(*bytes.Buffer)
-	defer pool.Put(buf)
-	defer buf.Reset()
-
-	buf.WriteString(hex.EncodeToString(net.ParseIP(getLocalIP()).To4()))
-	buf.WriteString(fmt.Sprintf("%x", unix&0xffffffff))
-	buf.WriteString(fmt.Sprintf("%04x", nano&0xffff))
-	buf.WriteString(fmt.Sprintf("%04x", pid&0xffff))
-	buf.WriteString(fmt.Sprintf("%06x", rand.Int31n(1<<24)))
-	buf.WriteString("5a")
-
-	return buf.String()
-}
-
-// genSpanID 生成
-func genSpanID() string {
-	return fmt.Sprintf("%x", rand.Int63())
-}
-
-// getLocalIP 获取本机IP地址
-func getLocalIP() string {
-	addr, err := net.InterfaceAddrs()
-	if err != nil {
-		return "0.0.0.0"
+func (t *Trace) cloneBaggage() map[string]string {
+	if t.Baggage == nil {
+		return nil
 	}
 
-	for _, addr := range addr {
-		if ip, ok := addr.(*net.IPNet); ok && ip.IP.IsGlobalUnicast() {
-			return ip.IP.String()
-		}
+	var baggage = make(map[string]string, len(t.Baggage))
+	for key, value := range t.Baggage {
+		baggage[key] = value
 	}
 
-	return "127.0.0.1"
+	return baggage
+}
+
+// String 序列化成字符串
+func (t *Trace) String() string {
+	data, _ := json.Marshal(t)
+	return string(data)
 }
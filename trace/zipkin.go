@@ -0,0 +1,98 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Zipkin v2 JSON编码, 参考zipkin2-api
+
+type zipkinSpan struct {
+	TraceID       string             `json:"traceId"`
+	ID            string             `json:"id"`
+	ParentID      string             `json:"parentId,omitempty"`
+	Name          string             `json:"name,omitempty"`
+	Kind          string             `json:"kind,omitempty"`
+	Timestamp     int64              `json:"timestamp"`
+	Duration      int64              `json:"duration"`
+	LocalEndpoint *zipkinEndpoint    `json:"localEndpoint,omitempty"`
+	Tags          map[string]string  `json:"tags,omitempty"`
+	Annotations   []zipkinAnnotation `json:"annotations,omitempty"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// NewZipkinExporter 创建Zipkin v2 JSON导出器, URL一般为http://zipkin:9411/api/v2/spans
+func NewZipkinExporter(option HTTPOption) Exporter {
+	return newHTTPExporter(option, EncodeZipkin)
+}
+
+// EncodeZipkin 编码为Zipkin v2 JSON请求体
+func EncodeZipkin(spans []*SpanData) ([]byte, error) {
+	var list = make([]zipkinSpan, 0, len(spans))
+	for _, span := range spans {
+		list = append(list, zipkinFromSpan(span))
+	}
+
+	return json.Marshal(list)
+}
+
+func zipkinFromSpan(span *SpanData) zipkinSpan {
+	var s = zipkinSpan{
+		TraceID:   W3CTraceID(span.TraceID),
+		ID:        W3CSpanID(span.SpanID),
+		Name:      span.Name,
+		Timestamp: zipkinTime(span.StartTime),
+		Duration:  int64(span.Duration() / time.Microsecond),
+	}
+
+	if span.ParentID != "" {
+		s.ParentID = W3CSpanID(span.ParentID)
+	}
+
+	// 内部调用不设置kind
+	switch span.Kind {
+	case KindServer, KindClient, KindProducer, KindConsumer:
+		s.Kind = strings.ToUpper(span.Kind.String())
+	}
+
+	if span.Service != "" {
+		s.LocalEndpoint = &zipkinEndpoint{ServiceName: span.Service}
+	}
+
+	if len(span.Attributes) > 0 || span.Status == StatusError {
+		s.Tags = make(map[string]string, len(span.Attributes)+1)
+		for key, value := range span.Attributes {
+			s.Tags[key] = fmt.Sprint(value)
+		}
+		if span.Status == StatusError {
+			s.Tags["error"] = span.StatusMessage
+			if s.Tags["error"] == "" {
+				s.Tags["error"] = "true"
+			}
+		}
+	}
+
+	for _, event := range span.Events {
+		s.Annotations = append(s.Annotations, zipkinAnnotation{
+			Timestamp: zipkinTime(event.Time),
+			Value:     event.Name,
+		})
+	}
+
+	return s
+}
+
+// 微秒时间戳
+func zipkinTime(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
@@ -0,0 +1,454 @@
+package zrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	url2 "net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 录制回放模式
+type Mode int
+
+const (
+	ModeReplay Mode = iota // 回放(未匹配的请求在非严格模式下透传到真实服务, 不录制)
+	ModeRecord             // 录制(始终请求真实服务并覆盖录制文件)
+	ModeAuto               // 录制文件存在时回放, 否则录制
+)
+
+// 脱敏后的替换值
+const Scrubbed = "[SCRUBBED]"
+
+// 录制的请求
+type CassetteRequest struct {
+	Method string      `json:"method" yaml:"method"`
+	URL    string      `json:"url" yaml:"url"`
+	Header http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body   string      `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// 录制的响应
+type CassetteResponse struct {
+	Code   int         `json:"code" yaml:"code"`
+	Header http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body   string      `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// 一次请求交互
+type Interaction struct {
+	Request  CassetteRequest  `json:"request" yaml:"request"`
+	Response CassetteResponse `json:"response" yaml:"response"`
+}
+
+// 请求匹配器, req为脱敏后的实际请求
+type Matcher func(req *CassetteRequest, record *CassetteRequest) bool
+
+// 录制回放选项
+type CassetteOption func(c *Cassette)
+
+// Cassette 录制回放传输层, 用于离线集成测试
+type Cassette struct {
+	mutex        sync.Mutex
+	mode         Mode
+	strict       bool
+	changed      bool
+	filename     string
+	matchers     []Matcher
+	headers      []string
+	fields       []string
+	queries      []string
+	transport    http.RoundTripper
+	used         []bool
+	interactions []*Interaction
+}
+
+// MatchMethod 匹配请求方法
+func MatchMethod(req *CassetteRequest, record *CassetteRequest) bool {
+	return strings.EqualFold(req.Method, record.Method)
+}
+
+// MatchURL 匹配URL(查询参数顺序无关)
+func MatchURL(req *CassetteRequest, record *CassetteRequest) bool {
+	u1, err1 := url2.Parse(req.URL)
+	u2, err2 := url2.Parse(record.URL)
+	if err1 != nil || err2 != nil {
+		return req.URL == record.URL
+	}
+
+	return u1.Scheme == u2.Scheme &&
+		u1.Host == u2.Host &&
+		u1.Path == u2.Path &&
+		reflect.DeepEqual(u1.Query(), u2.Query())
+}
+
+// MatchBody 请求体完全相等
+func MatchBody(req *CassetteRequest, record *CassetteRequest) bool {
+	return req.Body == record.Body
+}
+
+// MatchJSONBody 请求体JSON语义相等(字段顺序、空白无关), 非JSON时按原文比较
+func MatchJSONBody(req *CassetteRequest, record *CassetteRequest) bool {
+	var v1, v2 interface{}
+	if json.Unmarshal([]byte(req.Body), &v1) != nil || json.Unmarshal([]byte(record.Body), &v2) != nil {
+		return req.Body == record.Body
+	}
+	return reflect.DeepEqual(v1, v2)
+}
+
+// WithMatcher 设置请求匹配器, 默认匹配方法、URL和JSON请求体
+func WithMatcher(matchers ...Matcher) CassetteOption {
+	return func(c *Cassette) {
+		c.matchers = matchers
+	}
+}
+
+// WithStrict 严格模式, 回放时未匹配的请求直接返回错误
+func WithStrict(strict bool) CassetteOption {
+	return func(c *Cassette) {
+		c.strict = strict
+	}
+}
+
+// WithScrubHeader 脱敏请求头和响应头
+func WithScrubHeader(headers ...string) CassetteOption {
+	return func(c *Cassette) {
+		c.headers = append(c.headers, headers...)
+	}
+}
+
+// WithScrubJSON 脱敏JSON和表单请求体、响应体中的字段(任意层级)
+func WithScrubJSON(fields ...string) CassetteOption {
+	return func(c *Cassette) {
+		c.fields = append(c.fields, fields...)
+	}
+}
+
+// WithScrubQuery 脱敏URL查询参数
+func WithScrubQuery(queries ...string) CassetteOption {
+	return func(c *Cassette) {
+		c.queries = append(c.queries, queries...)
+	}
+}
+
+// WithTransport 录制时使用的真实传输层, 默认http.DefaultTransport
+func WithTransport(transport http.RoundTripper) CassetteOption {
+	return func(c *Cassette) {
+		c.transport = transport
+	}
+}
+
+// NewCassette 创建录制回放传输层, 文件扩展名为.yaml/.yml时使用YAML格式, 否则使用JSON格式
+func NewCassette(filename string, mode Mode, opts ...CassetteOption) (c *Cassette, err error) {
+	c = &Cassette{
+		mode:      mode,
+		filename:  filename,
+		matchers:  []Matcher{MatchMethod, MatchURL, MatchJSONBody},
+		transport: http.DefaultTransport,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.mode == ModeAuto {
+		if _, err = os.Stat(filename); err == nil {
+			c.mode = ModeReplay
+		} else if os.IsNotExist(err) {
+			c.mode = ModeRecord
+		} else {
+			return
+		}
+	}
+
+	if c.mode == ModeReplay {
+		if err = c.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Cassette) yaml() bool {
+	switch strings.ToLower(filepath.Ext(c.filename)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+func (c *Cassette) load() (err error) {
+	data, err := ioutil.ReadFile(c.filename)
+	if err != nil {
+		return
+	}
+
+	if c.yaml() {
+		err = yaml.Unmarshal(data, &c.interactions)
+	} else {
+		err = json.Unmarshal(data, &c.interactions)
+	}
+	if err != nil {
+		return
+	}
+
+	c.used = make([]bool, len(c.interactions))
+
+	return
+}
+
+// Save 保存录制内容, 未产生新录制时不写文件
+func (c *Cassette) Save() (err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.changed {
+		return
+	}
+
+	var data []byte
+	if c.yaml() {
+		data, err = yaml.Marshal(c.interactions)
+	} else {
+		data, err = json.MarshalIndent(c.interactions, "", "  ")
+	}
+	if err != nil {
+		return
+	}
+
+	if dir := filepath.Dir(c.filename); dir != "" {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return
+		}
+	}
+
+	if err = ioutil.WriteFile(c.filename, data, 0644); err != nil {
+		return
+	}
+
+	c.changed = false
+
+	return
+}
+
+// 脱敏JSON字段
+func (c *Cassette) scrubValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, value := range val {
+			if c.scrubField(key) {
+				val[key] = Scrubbed
+			} else {
+				val[key] = c.scrubValue(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range val {
+			val[i] = c.scrubValue(value)
+		}
+	}
+	return v
+}
+
+func (c *Cassette) scrubField(field string) bool {
+	for _, f := range c.fields {
+		if strings.EqualFold(f, field) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Cassette) scrubBody(contentType, body string) string {
+	if len(c.fields) == 0 || body == "" {
+		return body
+	}
+
+	if strings.Contains(contentType, "application/x-www-form-urlencoded") {
+		if values, err := url2.ParseQuery(body); err == nil {
+			for key := range values {
+				if c.scrubField(key) {
+					values.Set(key, Scrubbed)
+				}
+			}
+			return values.Encode()
+		}
+	}
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return body
+	}
+
+	data, err := json.Marshal(c.scrubValue(v))
+	if err != nil {
+		return body
+	}
+
+	return string(data)
+}
+
+func (c *Cassette) scrubHeader(header http.Header) http.Header {
+	header = header.Clone()
+	for _, key := range c.headers {
+		if header.Get(key) != "" {
+			header.Set(key, Scrubbed)
+		}
+	}
+	return header
+}
+
+func (c *Cassette) scrubURL(rawURL string) string {
+	if len(c.queries) == 0 {
+		return rawURL
+	}
+
+	u, err := url2.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	var query = u.Query()
+	for _, key := range c.queries {
+		if query.Has(key) {
+			query.Set(key, Scrubbed)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// 读取并还原请求体, 返回脱敏后的请求
+func (c *Cassette) request(req *http.Request) (record *CassetteRequest, err error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return
+		}
+		_ = req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	return &CassetteRequest{
+		Method: req.Method,
+		URL:    c.scrubURL(req.URL.String()),
+		Header: c.scrubHeader(req.Header),
+		Body:   c.scrubBody(req.Header.Get("Content-Type"), string(body)),
+	}, nil
+}
+
+func (c *Cassette) match(req *CassetteRequest) *Interaction {
+	var last *Interaction
+
+	for i, interaction := range c.interactions {
+		var matched = true
+		for _, matcher := range c.matchers {
+			if !matcher(req, &interaction.Request) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		// 按录制顺序回放, 全部回放过后重复使用最后一次匹配
+		if !c.used[i] {
+			c.used[i] = true
+			return interaction
+		}
+		last = interaction
+	}
+
+	return last
+}
+
+func (i *Interaction) response(req *http.Request) *http.Response {
+	var header = i.Response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Del("Content-Length")
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Response.Code, http.StatusText(i.Response.Code)),
+		StatusCode:    i.Response.Code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(i.Response.Body)),
+		ContentLength: int64(len(i.Response.Body)),
+		Request:       req,
+	}
+}
+
+// RoundTrip 实现http.RoundTripper
+func (c *Cassette) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	record, err := c.request(req)
+	if err != nil {
+		return
+	}
+
+	if c.mode == ModeReplay {
+		c.mutex.Lock()
+		interaction := c.match(record)
+		c.mutex.Unlock()
+
+		if interaction != nil {
+			return interaction.response(req), nil
+		}
+
+		if c.strict {
+			return nil, errors.New("cassette: no interaction matched for " + record.Method + " " + record.URL)
+		}
+
+		// 回放时透传的请求不写入录制文件
+		return c.transport.RoundTrip(req)
+	}
+
+	if resp, err = c.transport.RoundTrip(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var interaction = &Interaction{
+		Request: *record,
+		Response: CassetteResponse{
+			Code:   resp.StatusCode,
+			Header: c.scrubHeader(resp.Header),
+			Body:   c.scrubBody(resp.Header.Get("Content-Type"), string(body)),
+		},
+	}
+
+	c.mutex.Lock()
+	c.interactions = append(c.interactions, interaction)
+	c.used = append(c.used, true)
+	c.changed = true
+	c.mutex.Unlock()
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+// SetTransport 设置传输层(如录制回放Cassette)
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.client.Transport = transport
+}
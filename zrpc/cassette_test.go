@@ -0,0 +1,160 @@
+package zrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCassette(t *testing.T) {
+	for _, ext := range []string{".json", ".yaml"} {
+		t.Run(ext, func(t *testing.T) {
+			testCassette(t, filepath.Join(t.TempDir(), "cassette"+ext))
+		})
+	}
+}
+
+func testCassette(t *testing.T, filename string) {
+	var count int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		var req struct {
+			Name string `json:"name"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		fmt.Fprintf(w, `{"errno":0,"message":"ok","data":{"count":%d,"req":%q,"token":"secret"}}`, count, req.Name)
+	}))
+
+	type result struct {
+		Count int    `json:"count"`
+		Req   string `json:"req"`
+	}
+
+	var (
+		resp    result
+		ctx     = context.Background()
+		client  = New("test", 0, time.Second, nil)
+		request = map[string]interface{}{"name": "test", "secret": "123456", "age": 18}
+		options = []CassetteOption{
+			WithStrict(true),
+			WithScrubHeader("Set-Cookie", "Authorization"),
+			WithScrubJSON("secret", "token"),
+			WithScrubQuery("key"),
+		}
+	)
+
+	// 录制
+	cassette, err := NewCassette(filename, ModeAuto, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetTransport(cassette)
+
+	_, _, err = client.PostJSON(ctx, server.URL+"/api?key=abc&id=1", request, &resp, func(ctx context.Context, req *Request) {
+		req.Header.Set("Authorization", "Bearer secret")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, resp.Count)
+
+	_, _, err = client.Get(ctx, server.URL+"/api", NewForm(map[string]string{"id": "2"}), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, resp.Count)
+
+	if err = cassette.Save(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, strings.Contains(string(data), "123456"))
+	assert.False(t, strings.Contains(string(data), `:"secret`))
+	assert.False(t, strings.Contains(string(data), `:\"secret`))
+	assert.False(t, strings.Contains(string(data), "session=secret"))
+	assert.False(t, strings.Contains(string(data), "Bearer"))
+	assert.False(t, strings.Contains(string(data), "key=abc"))
+
+	// 回放(服务已关闭)
+	if cassette, err = NewCassette(filename, ModeAuto, options...); err != nil {
+		t.Fatal(err)
+	}
+	client.SetTransport(cassette)
+
+	// JSON字段顺序无关, 脱敏字段不影响匹配
+	_, _, err = client.PostJSON(ctx, server.URL+"/api?id=1&key=xyz", map[string]interface{}{"age": 18, "secret": "654321", "name": "test"}, &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, result{Count: 1, Req: "test"}, resp)
+
+	_, _, err = client.Get(ctx, server.URL+"/api", NewForm(map[string]string{"id": "2"}), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, result{Count: 2}, resp)
+
+	// 严格模式未匹配
+	_, _, err = client.Get(ctx, server.URL+"/api", NewForm(map[string]string{"id": "3"}), &resp)
+	assert.Error(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestCassette_Passthrough(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"errno":0,"message":"ok","data":%q}`, r.URL.Query().Get("id"))
+	}))
+	defer server.Close()
+
+	var (
+		resp     string
+		ctx      = context.Background()
+		client   = New("test", 0, time.Second, nil)
+		filename = filepath.Join(t.TempDir(), "cassette.json")
+	)
+
+	cassette, err := NewCassette(filename, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetTransport(cassette)
+
+	_, _, err = client.Get(ctx, server.URL, NewForm(map[string]string{"id": "1"}), &resp)
+	assert.NoError(t, err)
+	if err = cassette.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 非严格回放, 未匹配的请求透传但不录制
+	if cassette, err = NewCassette(filename, ModeReplay); err != nil {
+		t.Fatal(err)
+	}
+	client.SetTransport(cassette)
+
+	_, _, err = client.Get(ctx, server.URL, NewForm(map[string]string{"id": "2"}), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", resp)
+	if err = cassette.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(recorded), string(data))
+}
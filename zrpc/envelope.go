@@ -0,0 +1,204 @@
+package zrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/zooyer/miskit/errors"
+)
+
+// Envelope 响应信封解析, body为已读取的响应体, 业务错误返回errors.Error(保留下游错误码);
+// response为nil时只断言错误, 不解析数据
+type Envelope func(name string, resp *http.Response, body []byte, response interface{}) error
+
+// JSON:API错误对象
+type jsonAPIError struct {
+	Status string `json:"status,omitempty"`
+	Code   string `json:"code,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// RFC 7807 problem+json
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// 下游业务错误
+func remoteError(name string, code, errno int, message string) error {
+	return errors.NewMessage(errno, message, fmt.Errorf("%s: http response code:%d, errno:%d, message:%s", name, code, errno, message))
+}
+
+// HTTP状态错误
+func statusError(name string, resp *http.Response) error {
+	return fmt.Errorf("%s: http response code:%d, status:%s", name, resp.StatusCode, resp.Status)
+}
+
+func success(code int) bool {
+	return code >= http.StatusOK && code < http.StatusMultipleChoices
+}
+
+func unmarshal(data []byte, response interface{}) error {
+	if response == nil || len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(data, response)
+}
+
+// ErrnoEnvelope {errno,message,data}格式(默认), errno非0为业务错误
+func ErrnoEnvelope(name string, resp *http.Response, body []byte, response interface{}) (err error) {
+	var res Response
+
+	// 断言HTTP响应
+	if resp.StatusCode != http.StatusOK {
+		if err = json.Unmarshal(body, &res); err == nil && res.Errno != 0 && res.Message != "" {
+			return remoteError(name, resp.StatusCode, res.Errno, res.Message)
+		}
+		return statusError(name, resp)
+	}
+
+	// 解析业务层响应
+	if response != nil {
+		if err = json.Unmarshal(body, &res); err != nil {
+			return
+		}
+
+		// 断言业务层errno
+		if res.Errno != 0 {
+			return remoteError(name, resp.StatusCode, res.Errno, res.Message)
+		}
+
+		return json.Unmarshal(res.Data, response)
+	}
+
+	return
+}
+
+// CodeEnvelope {code,msg,data}格式, code不在ok中时为业务错误(ok为空时仅0成功)
+func CodeEnvelope(ok ...int) Envelope {
+	if len(ok) == 0 {
+		ok = []int{0}
+	}
+
+	return func(name string, resp *http.Response, body []byte, response interface{}) (err error) {
+		var res struct {
+			Code int             `json:"code"`
+			Msg  string          `json:"msg"`
+			Data json.RawMessage `json:"data,omitempty"`
+		}
+
+		if !success(resp.StatusCode) {
+			if err = json.Unmarshal(body, &res); err == nil && res.Code != 0 && res.Msg != "" {
+				return remoteError(name, resp.StatusCode, res.Code, res.Msg)
+			}
+			return statusError(name, resp)
+		}
+
+		if response == nil {
+			return
+		}
+
+		if err = json.Unmarshal(body, &res); err != nil {
+			return
+		}
+
+		for _, code := range ok {
+			if res.Code == code {
+				return unmarshal(res.Data, response)
+			}
+		}
+
+		return remoteError(name, resp.StatusCode, res.Code, res.Msg)
+	}
+}
+
+// RawEnvelope 无信封, 响应体直接解析到response, 非2xx为错误
+func RawEnvelope(name string, resp *http.Response, body []byte, response interface{}) error {
+	if !success(resp.StatusCode) {
+		return statusError(name, resp)
+	}
+
+	return unmarshal(body, response)
+}
+
+// JSONAPIEnvelope JSON:API格式{data,errors}, errors中第一个错误为业务错误, 错误码取code(数字)或status
+func JSONAPIEnvelope(name string, resp *http.Response, body []byte, response interface{}) (err error) {
+	var res struct {
+		Data   json.RawMessage `json:"data,omitempty"`
+		Errors []jsonAPIError  `json:"errors,omitempty"`
+	}
+
+	if err = json.Unmarshal(body, &res); err != nil {
+		if !success(resp.StatusCode) {
+			return statusError(name, resp)
+		}
+		if response == nil {
+			return nil
+		}
+		return
+	}
+
+	if len(res.Errors) > 0 {
+		var (
+			e       = res.Errors[0]
+			errno   = resp.StatusCode
+			message = e.Detail
+		)
+
+		if code, err := strconv.Atoi(e.Code); err == nil {
+			errno = code
+		} else if status, err := strconv.Atoi(e.Status); err == nil {
+			errno = status
+		}
+
+		if message == "" {
+			message = e.Title
+		}
+
+		return remoteError(name, resp.StatusCode, errno, message)
+	}
+
+	if !success(resp.StatusCode) {
+		return statusError(name, resp)
+	}
+
+	return unmarshal(res.Data, response)
+}
+
+// ProblemEnvelope RFC 7807 problem+json格式, 非2xx时解析Problem为业务错误(错误码取status), 2xx时响应体直接解析
+func ProblemEnvelope(name string, resp *http.Response, body []byte, response interface{}) (err error) {
+	if success(resp.StatusCode) {
+		return unmarshal(body, response)
+	}
+
+	var problem Problem
+	if err = json.Unmarshal(body, &problem); err != nil || problem.Title == "" && problem.Detail == "" {
+		return statusError(name, resp)
+	}
+
+	var (
+		errno   = problem.Status
+		message = problem.Detail
+	)
+
+	if errno == 0 {
+		errno = resp.StatusCode
+	}
+
+	if message == "" {
+		message = problem.Title
+	}
+
+	return remoteError(name, resp.StatusCode, errno, message)
+}
+
+// SetEnvelope 设置响应信封解析, 默认ErrnoEnvelope
+func (c *Client) SetEnvelope(envelope Envelope) {
+	c.envelope = envelope
+}
@@ -0,0 +1,76 @@
+package zrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zooyer/miskit/errors"
+)
+
+func TestEnvelope(t *testing.T) {
+	type data struct {
+		Name string `json:"name"`
+	}
+
+	var tests = []struct {
+		name     string
+		envelope Envelope
+		code     int
+		body     string
+		want     data
+		errno    int
+		message  string
+		wantErr  bool
+	}{
+		{name: "errno", envelope: nil, code: 200, body: `{"errno":0,"message":"ok","data":{"name":"a"}}`, want: data{"a"}},
+		{name: "errno-business", envelope: ErrnoEnvelope, code: 200, body: `{"errno":1001,"message":"not found"}`, errno: 1001, message: "not found", wantErr: true},
+		{name: "errno-http", envelope: ErrnoEnvelope, code: 500, body: `{"errno":1002,"message":"panic"}`, errno: 1002, message: "panic", wantErr: true},
+		{name: "errno-status", envelope: ErrnoEnvelope, code: 502, body: `bad gateway`, wantErr: true},
+		{name: "code", envelope: CodeEnvelope(0, 200), code: 200, body: `{"code":200,"msg":"success","data":{"name":"b"}}`, want: data{"b"}},
+		{name: "code-business", envelope: CodeEnvelope(), code: 200, body: `{"code":40001,"msg":"invalid ip"}`, errno: 40001, message: "invalid ip", wantErr: true},
+		{name: "raw", envelope: RawEnvelope, code: 200, body: `{"name":"c"}`, want: data{"c"}},
+		{name: "raw-status", envelope: RawEnvelope, code: 404, body: `{"name":"c"}`, wantErr: true},
+		{name: "jsonapi", envelope: JSONAPIEnvelope, code: 201, body: `{"data":{"name":"d"}}`, want: data{"d"}},
+		{name: "jsonapi-error", envelope: JSONAPIEnvelope, code: 422, body: `{"errors":[{"status":"422","code":"2001","title":"Invalid","detail":"name is required"}]}`, errno: 2001, message: "name is required", wantErr: true},
+		{name: "jsonapi-status", envelope: JSONAPIEnvelope, code: 403, body: `{"errors":[{"status":"403","title":"Forbidden"}]}`, errno: 403, message: "Forbidden", wantErr: true},
+		{name: "problem", envelope: ProblemEnvelope, code: 200, body: `{"name":"e"}`, want: data{"e"}},
+		{name: "problem-error", envelope: ProblemEnvelope, code: 409, body: `{"type":"about:blank","title":"Conflict","status":409,"detail":"version mismatch"}`, errno: 409, message: "version mismatch", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.code)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			var (
+				got    data
+				client = New("test", 0, time.Second, nil)
+			)
+
+			if tt.envelope != nil {
+				client.SetEnvelope(tt.envelope)
+			}
+
+			_, code, err := client.Get(context.Background(), server.URL, nil, &got)
+			assert.Equal(t, tt.code, code)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				return
+			}
+
+			assert.Error(t, err)
+			if tt.errno != 0 {
+				assert.True(t, errors.Is(err, tt.errno), err.Error())
+				assert.Equal(t, tt.message, err.(errors.Error).Message())
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package zrpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	url2 "net/url"
+	"strings"
+)
+
+// 上传文件
+type File struct {
+	Field  string    `json:"field"` // 表单字段名
+	Name   string    `json:"name"`  // 文件名
+	Type   string    `json:"type"`  // 文件类型, 默认application/octet-stream
+	Reader io.Reader `json:"-"`     // 文件内容
+}
+
+// multipart/form-data请求
+type Multipart struct {
+	Form  url2.Values `json:"form,omitempty"`
+	Files []File      `json:"files,omitempty"`
+}
+
+// 原始请求体([]byte、string、io.Reader), 按原样发送, 不按Content-Type编码
+type Raw struct {
+	Body interface{}
+}
+
+// 下载进度回调, total未知时为-1
+type Progress func(current, total int64)
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// 以管道方式流式写入请求体, 避免大文件读入内存
+func (m *Multipart) body() (io.Reader, string, error) {
+	var (
+		reader, writer = io.Pipe()
+		form           = multipart.NewWriter(writer)
+	)
+
+	go func() {
+		writer.CloseWithError(m.write(form))
+	}()
+
+	return reader, form.FormDataContentType(), nil
+}
+
+func (m *Multipart) write(form *multipart.Writer) (err error) {
+	for key, values := range m.Form {
+		for _, val := range values {
+			if err = form.WriteField(key, val); err != nil {
+				return
+			}
+		}
+	}
+
+	for _, file := range m.Files {
+		var header = make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(file.Field), quoteEscaper.Replace(file.Name)))
+		if file.Type != "" {
+			header.Set("Content-Type", file.Type)
+		} else {
+			header.Set("Content-Type", "application/octet-stream")
+		}
+
+		part, err := form.CreatePart(header)
+		if err != nil {
+			return err
+		}
+
+		if file.Reader != nil {
+			if _, err = io.Copy(part, file.Reader); err != nil {
+				return err
+			}
+		}
+	}
+
+	return form.Close()
+}
+
+// 带进度的写入器
+type progressWriter struct {
+	writer   io.Writer
+	current  int64
+	total    int64
+	progress Progress
+}
+
+func (p *progressWriter) Write(data []byte) (n int, err error) {
+	n, err = p.writer.Write(data)
+	p.current += int64(n)
+	if p.progress != nil {
+		p.progress(p.current, p.total)
+	}
+	return
+}
+
+// Download 下载文件到writer, 下载不受客户端整体超时限制, 由ctx控制生命周期
+func (c *Client) Download(ctx context.Context, url string, params url2.Values, writer io.Writer, progress Progress, opts ...Option) (n int64, code int, err error) {
+	var (
+		call   = c.newCall(ctx, http.MethodGet, url, params)
+		client = c.client
+		body   []byte
+	)
+
+	client.Timeout = 0
+
+	defer func() {
+		if err == nil {
+			body = []byte(fmt.Sprintf("<download %d bytes>", n))
+		}
+		c.report(ctx, call, body, code, err)
+	}()
+
+	resp, err := c.send(ctx, &client, call, "", opts...)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	if code = resp.StatusCode; code != http.StatusOK {
+		if body, err = ioutil.ReadAll(resp.Body); err != nil {
+			return
+		}
+		if err = c.decode(resp, body, nil); err == nil {
+			err = statusError(c.name, resp)
+		}
+		return 0, code, err
+	}
+
+	var w = &progressWriter{
+		writer:   writer,
+		total:    resp.ContentLength,
+		progress: progress,
+	}
+
+	if n, err = io.Copy(w, resp.Body); err != nil {
+		return
+	}
+
+	return n, code, nil
+}
@@ -0,0 +1,131 @@
+package zrpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_PostMultipart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, _ := ioutil.ReadAll(file)
+		fmt.Fprintf(w, `{"errno":0,"message":"ok","data":"%s:%s:%s:%s"}`, r.FormValue("name"), header.Filename, header.Header.Get("Content-Type"), data)
+	}))
+	defer server.Close()
+
+	var (
+		resp   string
+		client = New("test", 1, time.Second, nil)
+		files  = []File{{Field: "file", Name: "a.txt", Type: "text/plain", Reader: strings.NewReader("hello")}}
+	)
+
+	_, code, err := client.PostMultipart(context.Background(), server.URL, url.Values{"name": {"test"}}, files, &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "test:a.txt:text/plain:hello", resp)
+}
+
+func TestClient_Methods(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"errno":0,"message":"ok","data":%q}`, r.Method+" "+r.Header.Get("Content-Type")+" "+r.URL.RawQuery+" "+string(data))
+	}))
+	defer server.Close()
+
+	var (
+		resp   string
+		ctx    = context.Background()
+		client = New("test", 0, time.Second, nil)
+	)
+
+	_, _, err := client.PutJSON(ctx, server.URL, map[string]int{"a": 1}, &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, `PUT application/json  {"a":1}`, resp)
+
+	_, _, err = client.PatchJSON(ctx, server.URL, map[string]int{"b": 2}, &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, `PATCH application/json  {"b":2}`, resp)
+
+	_, _, err = client.Delete(ctx, server.URL, url.Values{"id": {"3"}}, &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, `DELETE  id=3 `, resp)
+
+	_, _, err = client.PostRaw(ctx, server.URL, "text/csv", []byte("a,b"), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, `POST text/csv  a,b`, resp)
+
+	_, _, err = client.Do(ctx, "PUT", "application/xml", server.URL, Raw{Body: bytes.NewBufferString("<a/>")}, &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, `PUT application/xml  <a/>`, resp)
+
+	// JSON请求中的string和[]byte仍按JSON编码
+	_, _, err = client.PostJSON(ctx, server.URL, "x", &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, `POST application/json  "x"`, resp)
+
+	_, _, err = client.PostJSON(ctx, server.URL, []byte("x"), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, `POST application/json  "eA=="`, resp)
+
+	str, err := Call[string, string](ctx, client, http.MethodPost, server.URL, "x")
+	assert.NoError(t, err)
+	assert.Equal(t, `POST application/json  "x"`, *str)
+}
+
+func TestClient_Download(t *testing.T) {
+	var content = bytes.Repeat([]byte("0123456789"), 10000)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") != "file" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	var (
+		buf     bytes.Buffer
+		current int64
+		total   int64
+		client  = New("test", 0, time.Second, nil)
+	)
+
+	n, code, err := client.Download(context.Background(), server.URL, url.Values{"name": {"file"}}, &buf, func(c, t int64) {
+		current, total = c, t
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(len(content)), n)
+	assert.Equal(t, int64(len(content)), current)
+	assert.Equal(t, int64(len(content)), total)
+	assert.Equal(t, content, buf.Bytes())
+
+	_, code, err = client.Download(context.Background(), server.URL, nil, &buf, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -0,0 +1,307 @@
+package zrpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zooyer/miskit/metric"
+)
+
+// 单次请求配置(通过Option设置)
+type callConfig struct {
+	timeout  time.Duration // 单次请求超时
+	override bool          // 是否覆盖客户端超时
+	hedge    bool          // 是否开启对冲请求
+	delay    time.Duration // 对冲延迟, 0表示使用p95延迟
+}
+
+type callConfigKey struct{}
+
+// 延迟统计窗口
+type latencyWindow struct {
+	mutex   sync.Mutex
+	index   int
+	samples []time.Duration
+}
+
+// 响应体关闭时取消请求上下文
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// 对冲请求结果
+type hedgeResult struct {
+	index int
+	resp  *http.Response
+	err   error
+}
+
+const (
+	latencyWindowSize = 128
+	latencyMinSamples = 20
+)
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func newLatencyWindow() *latencyWindow {
+	return &latencyWindow{
+		samples: make([]time.Duration, 0, latencyWindowSize),
+	}
+}
+
+func (w *latencyWindow) add(latency time.Duration) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.samples) < latencyWindowSize {
+		w.samples = append(w.samples, latency)
+		return
+	}
+
+	w.samples[w.index] = latency
+	w.index = (w.index + 1) % latencyWindowSize
+}
+
+// 最近请求的p95延迟, 样本不足时返回false
+func (w *latencyWindow) p95() (time.Duration, bool) {
+	w.mutex.Lock()
+	var samples = make([]time.Duration, len(w.samples))
+	copy(samples, w.samples)
+	w.mutex.Unlock()
+
+	if len(samples) < latencyMinSamples {
+		return 0, false
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i] < samples[j]
+	})
+
+	return samples[len(samples)*95/100], true
+}
+
+func getCallConfig(req *Request) *callConfig {
+	config, _ := (*http.Request)(req).Context().Value(callConfigKey{}).(*callConfig)
+	return config
+}
+
+// Timeout 单次请求超时(每次重试单独计算), 覆盖客户端超时, 仍受ctx deadline约束; timeout<=0表示仅使用ctx deadline
+func Timeout(timeout time.Duration) Option {
+	return func(ctx context.Context, req *Request) {
+		if config := getCallConfig(req); config != nil {
+			config.timeout = timeout
+			config.override = true
+		}
+	}
+}
+
+// Hedge 对冲请求, 首次请求超过delay未响应时发送第二次请求, 取先成功的响应并取消另一个,
+// 5xx/429响应视为失败, 全部失败时返回失败的响应;
+// delay<=0表示使用最近请求的p95延迟(样本不足时不对冲). 仅适用于幂等请求
+func Hedge(delay time.Duration) Option {
+	return func(ctx context.Context, req *Request) {
+		if config := getCallConfig(req); config != nil {
+			config.hedge = true
+			config.delay = delay
+		}
+	}
+}
+
+// 单次请求上下文
+func (c *callConfig) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.override && c.timeout > 0 {
+		return context.WithTimeout(ctx, c.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// 执行单次请求(含对冲)
+func (c *Client) attempt(client *http.Client, call *call) (*http.Response, error) {
+	if call.config.override {
+		var cli = *client
+		cli.Timeout = 0
+		client = &cli
+	}
+
+	if !call.config.hedge {
+		return c.try(client, call, call.req)
+	}
+
+	// 不可重放的请求体不对冲
+	if call.req.Body != nil && call.req.Body != http.NoBody && call.req.GetBody == nil {
+		return c.try(client, call, call.req)
+	}
+
+	var delay = call.config.delay
+	if delay <= 0 {
+		var ok bool
+		if delay, ok = c.latency.p95(); !ok {
+			return c.try(client, call, call.req)
+		}
+	}
+
+	return c.hedge(client, call, delay)
+}
+
+// 执行请求, 请求上下文在响应体关闭时取消
+func (c *Client) try(client *http.Client, call *call, req *http.Request) (*http.Response, error) {
+	ctx, cancel := call.config.context(req.Context())
+	defer func() {
+		if cancel != nil {
+			cancel()
+		}
+	}()
+
+	var start = time.Now()
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	c.latency.add(time.Since(start))
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	cancel = nil
+
+	return resp, nil
+}
+
+// 发送对冲请求
+func (c *Client) hedge(client *http.Client, call *call, delay time.Duration) (resp *http.Response, err error) {
+	var (
+		timer   = time.NewTimer(delay)
+		results = make(chan hedgeResult, 2)
+		cancels []context.CancelFunc
+		failed  *hedgeResult // 失败的响应(5xx/429)
+		pending int
+		hedged  bool
+	)
+	defer timer.Stop()
+
+	var launch = func(req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		pending++
+
+		go func() {
+			resp, err := c.try(client, call, req.WithContext(ctx))
+			results <- hedgeResult{index: index, resp: resp, err: err}
+		}()
+	}
+
+	launch(call.req)
+
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if hedged {
+				continue
+			}
+			// 请求体无法重放时不发起对冲, 也不计入对冲
+			var req = call.req.Clone(call.req.Context())
+			if call.req.GetBody != nil {
+				body, bodyErr := call.req.GetBody()
+				if bodyErr != nil {
+					continue
+				}
+				req.Body = body
+			}
+			launch(req)
+			hedged = true
+		case result := <-results:
+			pending--
+			// 5xx/429响应视为失败, 继续等待其他请求, 全部失败时返回该响应
+			if result.err == nil && hedgeFailed(result.resp) {
+				if !hedged {
+					timer.Stop()
+					result.resp.Body = &cancelBody{ReadCloser: result.resp.Body, cancel: cancels[result.index]}
+					return result.resp, nil
+				}
+				if failed != nil {
+					_ = failed.resp.Body.Close()
+					cancels[failed.index]()
+				}
+				failed = &result
+				continue
+			}
+
+			if result.err != nil {
+				if err == nil || result.index == 0 {
+					err = result.err
+				}
+				// 首次请求在对冲前失败, 交由重试处理
+				if !hedged {
+					cancels[0]()
+					return nil, err
+				}
+				continue
+			}
+
+			// 取消其他请求, 丢弃失败和迟到的响应
+			if failed != nil {
+				_ = failed.resp.Body.Close()
+			}
+			for i, cancel := range cancels {
+				if i != result.index {
+					cancel()
+				}
+			}
+			go func(pending int) {
+				for i := 0; i < pending; i++ {
+					if r := <-results; r.resp != nil {
+						_ = r.resp.Body.Close()
+					}
+				}
+			}(pending)
+
+			if hedged {
+				var winner = "primary"
+				if result.index > 0 {
+					winner = "hedge"
+				}
+				metric.Count("zrpc::hedge", 1, map[string]interface{}{
+					"name":   c.name,
+					"winner": winner,
+				})
+			}
+
+			result.resp.Body = &cancelBody{ReadCloser: result.resp.Body, cancel: cancels[result.index]}
+
+			return result.resp, nil
+		}
+	}
+
+	for i, cancel := range cancels {
+		if failed == nil || i != failed.index {
+			cancel()
+		}
+	}
+
+	if hedged {
+		metric.Count("zrpc::hedge", 1, map[string]interface{}{
+			"name":   c.name,
+			"winner": "none",
+		})
+	}
+
+	if failed != nil {
+		failed.resp.Body = &cancelBody{ReadCloser: failed.resp.Body, cancel: cancels[failed.index]}
+		return failed.resp, nil
+	}
+
+	return nil, err
+}
+
+// 对冲中视为失败的响应
+func hedgeFailed(resp *http.Response) bool {
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
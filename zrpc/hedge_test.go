@@ -0,0 +1,174 @@
+package zrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zooyer/miskit/metric"
+)
+
+func TestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprint(w, `{"errno":0,"message":"ok","data":"slow"}`)
+	}))
+	defer server.Close()
+
+	var (
+		resp   string
+		ctx    = context.Background()
+		client = New("test", 0, time.Second, nil)
+	)
+
+	_, _, err := client.Get(ctx, server.URL, nil, &resp, Timeout(50*time.Millisecond))
+	assert.Error(t, err)
+
+	_, _, err = client.Get(ctx, server.URL, nil, &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "slow", resp)
+
+	// 仅使用ctx deadline
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, _, err = client.Get(ctx, server.URL, nil, &resp, Timeout(0))
+	assert.Error(t, err)
+}
+
+func TestHedge(t *testing.T) {
+	var count int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 第一次请求很慢, 对冲请求很快
+		if atomic.AddInt32(&count, 1) == 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		fmt.Fprintf(w, `{"errno":0,"message":"ok","data":%d}`, atomic.LoadInt32(&count))
+	}))
+	defer server.Close()
+
+	var (
+		resp   int
+		start  = time.Now()
+		client = New("test", 0, 2*time.Second, nil)
+	)
+
+	_, _, err := client.Get(context.Background(), server.URL, nil, &resp, Hedge(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, resp)
+	assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+
+	// p95样本不足时不对冲
+	atomic.StoreInt32(&count, 10)
+	_, _, err = client.Get(context.Background(), server.URL, nil, &resp, Hedge(0))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(11), atomic.LoadInt32(&count))
+}
+
+func TestHedge_Failed(t *testing.T) {
+	var (
+		count   int32
+		primary int32 = http.StatusOK
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 首次请求较慢, 对冲请求很快但失败
+		if atomic.AddInt32(&count, 1)%2 == 1 {
+			time.Sleep(100 * time.Millisecond)
+			if code := int(atomic.LoadInt32(&primary)); code != http.StatusOK {
+				w.WriteHeader(code)
+				fmt.Fprint(w, `{"errno":1,"message":"primary"}`)
+				return
+			}
+			fmt.Fprint(w, `{"errno":0,"message":"ok","data":"primary"}`)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"errno":1,"message":"hedge"}`)
+	}))
+	defer server.Close()
+
+	var (
+		resp   string
+		client = New("test", 0, 2*time.Second, nil)
+	)
+
+	// 快速失败的响应不抢先于成功的响应
+	_, code, err := client.Get(context.Background(), server.URL, nil, &resp, Hedge(20*time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "primary", resp)
+
+	// 全部失败时返回失败的响应
+	atomic.StoreInt32(&primary, http.StatusTooManyRequests)
+	_, code, err = client.Get(context.Background(), server.URL, nil, &resp, Hedge(20*time.Millisecond))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, code)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&count))
+}
+
+func TestLatencyWindow(t *testing.T) {
+	var window = newLatencyWindow()
+
+	_, ok := window.p95()
+	assert.False(t, ok)
+
+	for i := 1; i <= 200; i++ {
+		window.add(time.Duration(i) * time.Millisecond)
+	}
+
+	p95, ok := window.p95()
+	assert.True(t, ok)
+	assert.Equal(t, 194*time.Millisecond, p95)
+}
+
+func TestHedge_GetBody(t *testing.T) {
+	var count int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return nil, errors.New("get body failed")
+	}
+
+	// 请求体无法重放时不发起对冲, 返回首次请求的响应
+	var client = New("hedge_get_body", 0, time.Second, nil)
+	resp, err := client.hedge(http.DefaultClient, &call{req: req}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+
+	var buf bytes.Buffer
+	assert.NoError(t, metric.WriteText(&buf, metric.Default.Gather()))
+	assert.NotContains(t, buf.String(), "hedge_get_body")
+}
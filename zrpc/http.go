@@ -15,6 +15,7 @@ import (
 	"reflect"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/zooyer/miskit/log"
 	"github.com/zooyer/miskit/metric"
@@ -23,12 +24,14 @@ import (
 
 // HTTP客户端
 type Client struct {
-	name    string
-	retry   int
-	timeout time.Duration
-	logger  *log.Logger
-	client  http.Client
-	option  []Option
+	name     string
+	retry    int
+	timeout  time.Duration
+	logger   *log.Logger
+	client   http.Client
+	option   []Option
+	latency  *latencyWindow
+	envelope Envelope
 }
 
 // HTTP请求
@@ -110,6 +113,7 @@ func New(name string, retry int, timeout time.Duration, logger *log.Logger, opts
 		timeout: timeout,
 		logger:  logger,
 		option:  opts,
+		latency: newLatencyWindow(),
 		client: http.Client{
 			Transport: &http.Transport{
 				DialContext: (&net.Dialer{
@@ -145,48 +149,126 @@ func (c *Client) PostJSON(ctx context.Context, url string, request, response int
 	return c.post(ctx, url, "application/json", request, response, opts...)
 }
 
-// 创建HTTP请求
-func (c *Client) newRequest(ctx context.Context, method, contentType, url string, request interface{}, opts ...Option) (req *http.Request, err error) {
-	var body io.Reader
-
-	if method != "GET" {
-		content, _, _ := mime.ParseMediaType(contentType)
-		switch content {
-		case "application/json":
-			data, err := json.Marshal(request)
-			if err != nil {
-				return nil, err
+// PUT JSON请求
+func (c *Client) PutJSON(ctx context.Context, url string, request, response interface{}, opts ...Option) (data []byte, code int, err error) {
+	return c.do(ctx, "PUT", "application/json", url, request, response, opts...)
+}
+
+// PATCH JSON请求
+func (c *Client) PatchJSON(ctx context.Context, url string, request, response interface{}, opts ...Option) (data []byte, code int, err error) {
+	return c.do(ctx, "PATCH", "application/json", url, request, response, opts...)
+}
+
+// DELETE请求, 参数以查询参数发送
+func (c *Client) Delete(ctx context.Context, url string, params url2.Values, response interface{}, opts ...Option) (data []byte, code int, err error) {
+	return c.do(ctx, "DELETE", "", url, params, response, opts...)
+}
+
+// POST原始请求体([]byte、string、io.Reader), Content-Type任意
+func (c *Client) PostRaw(ctx context.Context, url, contentType string, body, response interface{}, opts ...Option) (data []byte, code int, err error) {
+	return c.post(ctx, url, contentType, Raw{Body: body}, response, opts...)
+}
+
+// POST multipart/form-data请求(文件上传)
+func (c *Client) PostMultipart(ctx context.Context, url string, form url2.Values, files []File, response interface{}, opts ...Option) (data []byte, code int, err error) {
+	return c.post(ctx, url, "multipart/form-data", &Multipart{Form: form, Files: files}, response, opts...)
+}
+
+// 通用请求, request可以是表单、JSON对象、Multipart或Raw原始请求体
+func (c *Client) Do(ctx context.Context, method, contentType, url string, request, response interface{}, opts ...Option) (data []byte, code int, err error) {
+	return c.do(ctx, method, contentType, url, request, response, opts...)
+}
+
+// 创建请求体, 返回实际的Content-Type(multipart需要带boundary)
+func (c *Client) newBody(contentType string, request interface{}) (body io.Reader, _ string, err error) {
+	// 原始请求体, 不按Content-Type编码
+	if raw, ok := request.(Raw); ok {
+		switch body := raw.Body.(type) {
+		case []byte:
+			return bytes.NewReader(body), contentType, nil
+		case string:
+			return strings.NewReader(body), contentType, nil
+		case io.Reader:
+			return body, contentType, nil
+		default:
+			return nil, "", fmt.Errorf("not support raw body type: %T", raw.Body)
+		}
+	}
+
+	content, _, _ := mime.ParseMediaType(contentType)
+	switch content {
+	case "application/json":
+		data, err := json.Marshal(request)
+		if err != nil {
+			return nil, "", err
+		}
+		body = bytes.NewReader(data)
+	case "application/x-www-form-urlencoded":
+		switch form := request.(type) {
+		case url2.Values:
+			body = strings.NewReader(form.Encode())
+		case map[string]string:
+			var values = make(url2.Values)
+			for key, val := range form {
+				values.Set(key, val)
 			}
-			body = bytes.NewReader(data)
-		case "application/x-www-form-urlencoded":
-			switch form := request.(type) {
-			case url2.Values:
-				body = strings.NewReader(form.Encode())
-			case map[string]string:
-				var values = make(url2.Values)
-				for key, val := range form {
-					values.Set(key, val)
-				}
-				body = strings.NewReader(values.Encode())
-			case map[string]interface{}:
-				var values = make(url2.Values)
-				for key, val := range form {
-					values.Set(key, fmt.Sprint(val))
-				}
-				body = strings.NewReader(values.Encode())
-			default:
-				return nil, errors.New("not support content type and request type:" + contentType)
+			body = strings.NewReader(values.Encode())
+		case map[string]interface{}:
+			var values = make(url2.Values)
+			for key, val := range form {
+				values.Set(key, fmt.Sprint(val))
 			}
+			body = strings.NewReader(values.Encode())
+		default:
+			return nil, "", errors.New("not support content type and request type:" + contentType)
+		}
+	case "multipart/form-data":
+		switch form := request.(type) {
+		case Multipart:
+			return form.body()
+		case *Multipart:
+			return form.body()
 		default:
-			return nil, errors.New("not support content type:" + contentType)
+			return nil, "", errors.New("not support content type and request type:" + contentType)
 		}
+	default:
+		return nil, "", errors.New("not support content type:" + contentType)
 	}
 
+	return body, contentType, nil
+}
+
+// 是否以查询参数发送请求, GET/HEAD及未指定Content-Type的DELETE请求不带请求体
+func queryMethod(method, contentType string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodDelete && contentType == ""
+}
+
+// 创建HTTP请求
+func (c *Client) newRequest(ctx context.Context, method, contentType, url string, request interface{}, opts ...Option) (req *http.Request, err error) {
+	var (
+		body  io.Reader
+		query = queryMethod(method, contentType)
+	)
+
+	if !query {
+		if body, contentType, err = c.newBody(contentType, request); err != nil {
+			return
+		}
+	}
+
+	defer func() {
+		if closer, ok := body.(io.Closer); ok && err != nil {
+			_ = closer.Close()
+		}
+	}()
+
 	if req, err = http.NewRequestWithContext(ctx, method, url, body); err != nil {
 		return
 	}
 
-	req.Header.Set("Content-Type", contentType)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 
 	for _, opt := range append(c.option, opts...) {
 		if opt != nil {
@@ -194,7 +276,7 @@ func (c *Client) newRequest(ctx context.Context, method, contentType, url string
 		}
 	}
 
-	if method == "GET" && request != nil {
+	if query && request != nil {
 		var query = req.URL.Query()
 		switch form := request.(type) {
 		case url2.Values:
@@ -222,111 +304,197 @@ func (c *Client) newRequest(ctx context.Context, method, contentType, url string
 }
 
 func (c *Client) marshalJSON(v interface{}) string {
+	if raw, ok := v.(Raw); ok {
+		switch body := raw.Body.(type) {
+		case string:
+			return body
+		case []byte:
+			if utf8.Valid(body) {
+				return string(body)
+			}
+			return fmt.Sprintf("<binary %d bytes>", len(body))
+		default:
+			return "<stream>"
+		}
+	}
+
 	data, _ := json.Marshal(v)
 	return string(data)
 }
 
-// 执行HTTP请求
-func (c *Client) do(ctx context.Context, method, contentType, url string, request, response interface{}, opts ...Option) (body []byte, code int, err error) {
+// 请求信息(用于记录日志和监控)
+type call struct {
+	start   time.Time
+	method  string
+	url     string
+	retry   int
+	items   int
+	stream  bool
+	config  callConfig
+	request interface{}
+	req     *http.Request
+	span    *trace.Span
+	child   *trace.Trace
+}
+
+func (c *Client) newCall(ctx context.Context, method, url string, request interface{}) *call {
+	var call = &call{
+		start:   time.Now(),
+		method:  method,
+		url:     url,
+		request: request,
+	}
+
+	// 客户端span, 下游服务以此为父节点
+	if trace.Get(ctx) != nil {
+		_, call.span = trace.StartSpan(ctx, "HTTP "+method, trace.WithKind(trace.KindClient), trace.WithAttributes(map[string]interface{}{
+			"http.method": method,
+			"rpc.service": c.name,
+		}))
+		call.child = call.span.Trace()
+	}
+
+	return call
+}
+
+// 记录请求日志和监控
+func (c *Client) report(ctx context.Context, call *call, body []byte, code int, err error) {
 	var (
-		start = time.Now()
-		retry int
-		req   *http.Request
-		resp  *http.Response
-		child = trace.Get(ctx).GenChild()
+		caller  = ""
+		callee  = call.url
+		latency = time.Since(call.start)
 	)
 
-	defer func() {
-		var (
-			code    = code
-			caller  = ""
-			callee  = url
-			latency = time.Since(start)
-		)
-
-		if req != nil {
-			callee = req.URL.Path
-		} else {
-			callee = strings.TrimPrefix(callee, "http://")
-			callee = strings.TrimPrefix(callee, "https://")
-			if index := strings.Index(callee, "/"); index >= 0 {
-				callee = callee[index:]
-			}
+	if call.req != nil {
+		callee = call.req.URL.Path
+	} else {
+		callee = strings.TrimPrefix(callee, "http://")
+		callee = strings.TrimPrefix(callee, "https://")
+		if index := strings.Index(callee, "/"); index >= 0 {
+			callee = callee[index:]
 		}
+	}
 
-		if err != nil && (code == 0 || code == http.StatusOK) {
-			code = 599
-		}
+	if err != nil && (code == 0 || code == http.StatusOK) {
+		code = 599
+	}
 
-		if child != nil && child.Request != nil {
-			caller = child.Request.URL.Path
-		}
+	if call.child != nil && call.child.Request != nil {
+		caller = call.child.Request.URL.Path
+	}
 
-		metric.Rpc("zrpc", caller, callee, code, latency, map[string]interface{}{
-			"name": c.name,
-		})
+	metric.Rpc("zrpc", caller, callee, code, latency, map[string]interface{}{
+		"name": c.name,
+	})
+
+	if call.span != nil {
+		call.span.SetName("HTTP " + call.method + " " + callee)
+		call.span.SetAttribute("http.url", callee)
+		call.span.SetAttribute("http.status_code", code)
+		call.span.SetAttribute("rpc.retry", call.retry)
+		if err != nil {
+			call.span.RecordError(err)
+		}
+		call.span.End()
+	}
 
-		if c.logger != nil {
-			output := c.logger.Info
-			if err != nil {
-				output = c.logger.Error
-			}
+	if c.logger != nil {
+		output := c.logger.Info
+		if err != nil {
+			output = c.logger.Error
+		}
 
-			c.logger.Tag(
-				false,
-				"rpc", "http",
-				"name", c.name,
-				"method", method,
-				"latency", latency,
-				"retry", retry,
-			)
-			if child != nil {
-				c.logger.Tag(false, "cspan_id", child.SpanID)
-			}
-			if req != nil {
-				c.logger.Tag(false, "url", req.URL.String())
+		c.logger.Tag(
+			false,
+			"rpc", "http",
+			"name", c.name,
+			"method", call.method,
+			"latency", latency,
+			"retry", call.retry,
+		)
+		if call.child != nil {
+			c.logger.Tag(false, "cspan_id", call.child.SpanID)
+		}
+		if call.req != nil {
+			c.logger.Tag(false, "url", call.req.URL.String())
+		} else {
+			c.logger.Tag(false, "url", call.url)
+		}
+		if call.request != nil {
+			c.logger.Tag(false, "req", c.marshalJSON(call.request))
+		}
+		if call.stream {
+			c.logger.Tag(false, "stream", call.items)
+		}
+		if bytes.ContainsAny(body, "\t\r\n") {
+			if data, err := json.Marshal(json.RawMessage(body)); err == nil {
+				body = data
 			} else {
-				c.logger.Tag(false, "url", url)
-			}
-			if request != nil {
-				c.logger.Tag(false, "req", c.marshalJSON(request))
+				body = bytes.ReplaceAll(body, []byte("\r"), nil)
+				body = bytes.ReplaceAll(body, []byte("\n"), nil)
 			}
-			if bytes.ContainsAny(body, "\t\r\n") {
-				if data, err := json.Marshal(json.RawMessage(body)); err == nil {
-					body = data
-				} else {
-					body = bytes.ReplaceAll(body, []byte("\r"), nil)
-					body = bytes.ReplaceAll(body, []byte("\n"), nil)
-				}
-			}
-			if len(body) > 0 {
-				c.logger.Tag(false, "resp", string(body))
-			}
-			if err != nil {
-				c.logger.Tag(false, "error", err.Error())
-			}
-
-			output(ctx)
 		}
-	}()
+		if len(body) > 0 {
+			c.logger.Tag(false, "resp", string(body))
+		}
+		if err != nil {
+			c.logger.Tag(false, "error", err.Error())
+		}
+
+		output(ctx)
+	}
+}
 
-	// 创建请求
-	if req, err = c.newRequest(ctx, method, contentType, url, request, opts...); err != nil {
+// 发送HTTP请求(含重试)
+func (c *Client) send(ctx context.Context, client *http.Client, call *call, contentType string, opts ...Option) (resp *http.Response, err error) {
+	// 创建请求(单次请求配置通过ctx传递给Option)
+	ctx = context.WithValue(ctx, callConfigKey{}, &call.config)
+	if call.req, err = c.newRequest(ctx, call.method, contentType, call.url, call.request, opts...); err != nil {
 		return
 	}
 
 	// 设置trace
-	if child != nil {
-		child.SetHeader(req.Header)
+	if call.child != nil {
+		call.child.SetHeader(call.req.Header)
 	}
 
 	// 请求重试
 	for i := 0; i < c.retry+1; i++ {
-		if resp, err = c.client.Do(req); err == nil {
+		if i > 0 && call.req.Body != nil && call.req.Body != http.NoBody {
+			// 不可重放的请求体(如文件流)不重试
+			if call.req.GetBody == nil {
+				break
+			}
+			if call.req.Body, err = call.req.GetBody(); err != nil {
+				return
+			}
+		}
+		if resp, err = c.attempt(client, call); err == nil {
 			break
 		}
-		retry++
+		call.retry++
 	}
+
+	return
+}
+
+// 解析HTTP响应
+func (c *Client) decode(resp *http.Response, body []byte, response interface{}) error {
+	var envelope = c.envelope
+	if envelope == nil {
+		envelope = ErrnoEnvelope
+	}
+
+	return envelope(c.name, resp, body, response)
+}
+
+// 执行HTTP请求
+func (c *Client) do(ctx context.Context, method, contentType, url string, request, response interface{}, opts ...Option) (body []byte, code int, err error) {
+	var call = c.newCall(ctx, method, url, request)
+
+	defer func() { c.report(ctx, call, body, code, err) }()
+
+	resp, err := c.send(ctx, &c.client, call, contentType, opts...)
 	if err != nil {
 		return
 	}
@@ -337,35 +505,26 @@ func (c *Client) do(ctx context.Context, method, contentType, url string, reques
 		return
 	}
 
-	// 断言HTTP响应
-	if code = resp.StatusCode; code != http.StatusOK {
-		var res Response
-		if err = json.Unmarshal(body, &res); err == nil && res.Errno != 0 && res.Message != "" {
-			if res.Message != "" {
-				return body, code, errors.New(res.Message)
-			}
-			return body, code, fmt.Errorf("%s: http resonse code:%d, errno:%d, message:%s", c.name, code, res.Errno, res.Message)
-		}
-		return body, code, fmt.Errorf("%s: http response code:%d, status:%s", c.name, resp.StatusCode, resp.Status)
-	}
+	code = resp.StatusCode
 
-	// 解析业务层响应
-	if response != nil {
-		// 解析body
-		var res Response
-		if err = json.Unmarshal(body, &res); err != nil {
-			return body, code, err
-		}
+	return body, code, c.decode(resp, body, response)
+}
 
-		// 断言业务层errno
-		if errno := res.Errno; errno != 0 {
-			return body, code, fmt.Errorf("%s: http resonse code:%d, errno:%d, message:%s", c.name, code, res.Errno, res.Message)
-		}
+// Call 泛型请求, GET/HEAD/DELETE请求参数通过NewForm转换为查询参数, 其他请求以JSON格式发送
+func Call[Req, Resp any](ctx context.Context, c *Client, method, url string, req Req, opts ...Option) (*Resp, error) {
+	var (
+		resp        = new(Resp)
+		request     interface{}
+		contentType = "application/json"
+	)
 
-		if err = json.Unmarshal(res.Data, response); err != nil {
-			return body, code, err
-		}
+	if request = req; queryMethod(method, "") {
+		request, contentType = NewForm(req), ""
+	}
+
+	if _, _, err := c.do(ctx, method, contentType, url, request, resp, opts...); err != nil {
+		return nil, err
 	}
 
-	return body, code, nil
+	return resp, nil
 }
@@ -3,9 +3,11 @@ package zrpc
 import (
 	"context"
 	"encoding/json"
+	"github.com/stretchr/testify/assert"
 	"github.com/zooyer/miskit/log"
 	"github.com/zooyer/miskit/trace"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 	"time"
@@ -56,3 +58,35 @@ func TestNew(t *testing.T) {
 	t.Log(code)
 	t.Log(string(data))
 }
+
+func TestClient_Sampling(t *testing.T) {
+	var sampled = make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sampled <- r.Header.Get("traceparent")[53:]
+		w.Write([]byte(`{"errno":0}`))
+	}))
+	defer server.Close()
+
+	var (
+		exporter = trace.NewMemoryExporter()
+		client   = New("test", 0, time.Second, nil)
+	)
+	trace.RegisterProcessor(trace.NewSimpleProcessor(exporter))
+	defer trace.Shutdown(context.Background())
+
+	// 未采样的trace不记录客户端span, 采样标记传给下游
+	var info = trace.New(nil, "test")
+	info.Flags = 0
+	_, _, err := client.Get(trace.Set(context.Background(), info), server.URL, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "00", <-sampled)
+	assert.Len(t, exporter.Spans(), 0)
+
+	info.Flags = trace.FlagSampled
+	_, _, err = client.Get(trace.Set(context.Background(), info), server.URL, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "01", <-sampled)
+	if spans := exporter.Spans(); assert.Len(t, spans, 1) {
+		assert.Equal(t, info.SpanID, spans[0].ParentID)
+	}
+}
@@ -0,0 +1,315 @@
+package zrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Event SSE事件(text/event-stream)
+type Event struct {
+	ID    string `json:"id,omitempty"`
+	Event string `json:"event,omitempty"`
+	Data  []byte `json:"data,omitempty"`
+	Retry int    `json:"retry,omitempty"`
+}
+
+// Stream 流式响应迭代器, 使用完毕后需调用Close;
+// Next/Item需在同一goroutine中调用(调用Chan后由其goroutine读取), Err和Close可在任意goroutine中调用
+type Stream[T any] struct {
+	ctx    context.Context
+	mutex  sync.Mutex
+	err    error
+	item   T
+	call   *call
+	resp   *http.Response
+	client *Client
+	closed bool
+	done   chan struct{} // Close时关闭, 结束Chan的goroutine
+	next   func() (T, error)
+}
+
+// 单行最大长度
+const maxStreamLine = 4 << 20
+
+func newStream[T any](ctx context.Context, c *Client, call *call, resp *http.Response) *Stream[T] {
+	return &Stream[T]{
+		ctx:    ctx,
+		call:   call,
+		resp:   resp,
+		client: c,
+		done:   make(chan struct{}),
+	}
+}
+
+// Next 读取下一个元素, 读取结束或出错时返回false
+func (s *Stream[T]) Next() bool {
+	s.mutex.Lock()
+	if s.closed || s.err != nil {
+		s.mutex.Unlock()
+		return false
+	}
+	s.mutex.Unlock()
+
+	// 读取时不持有锁, Close关闭响应体以中断阻塞的读取
+	item, err := s.next()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return false
+	}
+	if err != nil {
+		s.err = err
+		return false
+	}
+
+	s.item = item
+	s.call.items++
+
+	return true
+}
+
+// Item 当前元素
+func (s *Stream[T]) Item() T {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.item
+}
+
+// Err 读取过程中的错误, 正常结束返回nil
+func (s *Stream[T]) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+func (s *Stream[T]) setErr(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Close 关闭响应并记录日志和监控
+func (s *Stream[T]) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	s.mutex.Unlock()
+
+	err := s.resp.Body.Close()
+	s.client.report(s.ctx, s.call, nil, s.resp.StatusCode, s.Err())
+
+	return err
+}
+
+// Chan 以通道方式读取, 通道关闭时流已关闭, 可通过Err获取错误; 调用Close可提前结束
+func (s *Stream[T]) Chan() <-chan T {
+	var ch = make(chan T)
+
+	go func() {
+		defer close(ch)
+		defer s.Close()
+
+		for s.Next() {
+			select {
+			case ch <- s.Item():
+			case <-s.done:
+				return
+			case <-s.ctx.Done():
+				s.setErr(s.ctx.Err())
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// 发起流式请求, 流式响应不受客户端整体超时限制, 由ctx控制生命周期
+func stream[T any](ctx context.Context, c *Client, method, url, accept string, request interface{}, opts ...Option) (s *Stream[T], err error) {
+	var (
+		call        = c.newCall(ctx, method, url, request)
+		client      = c.client
+		contentType = "application/json"
+	)
+
+	call.stream = true
+	client.Timeout = 0
+
+	if method == http.MethodGet {
+		contentType = ""
+	}
+
+	opts = append(opts, func(ctx context.Context, req *Request) {
+		req.Header.Set("Accept", accept)
+	})
+
+	resp, err := c.send(ctx, &client, call, contentType, opts...)
+	if err != nil {
+		c.report(ctx, call, nil, 0, err)
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err == nil {
+			if err = c.decode(resp, body, nil); err == nil {
+				err = statusError(c.name, resp)
+			}
+		}
+		c.report(ctx, call, body, resp.StatusCode, err)
+		return nil, err
+	}
+
+	return newStream[T](ctx, c, call, resp), nil
+}
+
+func newScanner(s *bufio.Scanner) *bufio.Scanner {
+	s.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	return s
+}
+
+// Events 请求SSE(text/event-stream)接口, 返回原始事件
+func Events(ctx context.Context, c *Client, method, url string, request interface{}, opts ...Option) (*Stream[Event], error) {
+	s, err := stream[Event](ctx, c, method, url, "text/event-stream", request, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var scanner = newScanner(bufio.NewScanner(s.resp.Body))
+
+	s.next = func() (event Event, err error) {
+		var (
+			data    [][]byte
+			hasData bool
+		)
+
+		for scanner.Scan() {
+			line := bytes.TrimSuffix(scanner.Bytes(), []byte("\r"))
+
+			// 空行分发事件
+			if len(line) == 0 {
+				if !hasData {
+					event = Event{}
+					continue
+				}
+				event.Data = bytes.Join(data, []byte("\n"))
+				return event, nil
+			}
+
+			// 注释
+			if line[0] == ':' {
+				continue
+			}
+
+			field, value := line, []byte(nil)
+			if index := bytes.IndexByte(line, ':'); index >= 0 {
+				field, value = line[:index], bytes.TrimPrefix(line[index+1:], []byte(" "))
+			}
+
+			switch string(field) {
+			case "data":
+				hasData = true
+				data = append(data, append([]byte(nil), value...))
+			case "event":
+				event.Event = string(value)
+			case "id":
+				event.ID = string(value)
+			case "retry":
+				if retry, err := strconv.Atoi(string(value)); err == nil {
+					event.Retry = retry
+				}
+			}
+		}
+
+		if err = scanner.Err(); err != nil {
+			return
+		}
+
+		if hasData {
+			event.Data = bytes.Join(data, []byte("\n"))
+			return event, nil
+		}
+
+		return event, io.EOF
+	}
+
+	return s, nil
+}
+
+// StreamSSE 请求SSE接口, 将每个事件的data按JSON解析为T
+func StreamSSE[T any](ctx context.Context, c *Client, method, url string, request interface{}, opts ...Option) (*Stream[T], error) {
+	events, err := Events(ctx, c, method, url, request, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newStream[T](events.ctx, c, events.call, events.resp)
+
+	s.next = func() (item T, err error) {
+		for {
+			event, err := events.next()
+			if err != nil {
+				return item, err
+			}
+
+			if len(bytes.TrimSpace(event.Data)) == 0 {
+				continue
+			}
+
+			return item, json.Unmarshal(event.Data, &item)
+		}
+	}
+
+	return s, nil
+}
+
+// StreamNDJSON 请求NDJSON(application/x-ndjson)分块响应接口, 每行按JSON解析为T
+func StreamNDJSON[T any](ctx context.Context, c *Client, method, url string, request interface{}, opts ...Option) (*Stream[T], error) {
+	s, err := stream[T](ctx, c, method, url, "application/x-ndjson", request, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var scanner = newScanner(bufio.NewScanner(s.resp.Body))
+
+	s.next = func() (item T, err error) {
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+
+			return item, json.Unmarshal(line, &item)
+		}
+
+		if err = scanner.Err(); err != nil {
+			return
+		}
+
+		return item, io.EOF
+	}
+
+	return s, nil
+}
@@ -0,0 +1,182 @@
+package zrpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zooyer/miskit/errors"
+)
+
+type streamItem struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+}
+
+func TestCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet || r.Method == http.MethodDelete {
+			fmt.Fprintf(w, `{"errno":0,"message":"ok","data":{"index":1,"name":"%s"}}`, r.URL.Query().Get("name"))
+			return
+		}
+		fmt.Fprint(w, `{"errno":10,"message":"invalid"}`)
+	}))
+	defer server.Close()
+
+	var client = New("test", 0, time.Second, nil)
+
+	item, err := Call[map[string]string, streamItem](context.Background(), client, http.MethodGet, server.URL, map[string]string{"name": "get"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, streamItem{Index: 1, Name: "get"}, *item)
+
+	// DELETE请求参数同样作为查询参数发送
+	item, err = Call[map[string]string, streamItem](context.Background(), client, http.MethodDelete, server.URL, map[string]string{"name": "delete"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, streamItem{Index: 1, Name: "delete"}, *item)
+
+	_, err = Call[streamItem, streamItem](context.Background(), client, http.MethodPost, server.URL, streamItem{Index: 2})
+	assert.Error(t, err)
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "{\"index\":%d,\"name\":\"item\"}\n\n", i)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	var client = New("test", 0, time.Second, nil)
+
+	stream, err := StreamNDJSON[streamItem](context.Background(), client, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var items []streamItem
+	for stream.Next() {
+		items = append(items, stream.Item())
+	}
+
+	assert.NoError(t, stream.Err())
+	assert.Equal(t, []streamItem{{0, "item"}, {1, "item"}, {2, "item"}}, items)
+}
+
+func TestStreamSSE(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": comment\n\n")
+		fmt.Fprint(w, "id: 1\nevent: message\ndata: {\"index\":1,\r\ndata: \"name\":\"a\"}\n\n")
+		fmt.Fprint(w, "retry: 100\n\n")
+		fmt.Fprint(w, "id: 2\ndata: {\"index\":2,\"name\":\"b\"}\n")
+	}))
+	defer server.Close()
+
+	var client = New("test", 0, time.Second, nil)
+
+	events, err := Events(context.Background(), client, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var list []Event
+	for event := range events.Chan() {
+		list = append(list, event)
+	}
+	assert.NoError(t, events.Err())
+	assert.Equal(t, []Event{
+		{ID: "1", Event: "message", Data: []byte("{\"index\":1,\n\"name\":\"a\"}")},
+		{ID: "2", Data: []byte(`{"index":2,"name":"b"}`)},
+	}, list)
+
+	stream, err := StreamSSE[streamItem](context.Background(), client, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []streamItem
+	for item := range stream.Chan() {
+		items = append(items, item)
+	}
+	assert.NoError(t, stream.Err())
+	assert.Equal(t, []streamItem{{1, "a"}, {2, "b"}}, items)
+}
+
+func TestStream_ChanClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 0; ; i++ {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Millisecond):
+			}
+			fmt.Fprintf(w, "{\"index\":%d,\"name\":\"item\"}\n", i)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	var client = New("test", 0, time.Second, nil)
+
+	stream, err := StreamNDJSON[streamItem](context.Background(), client, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ch = stream.Chan()
+	assert.Equal(t, 0, (<-ch).Index)
+
+	// Chan的goroutine读取时并发调用Err和Close
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = stream.Err()
+		}
+	}()
+	assert.NoError(t, stream.Close())
+	<-done
+
+	// 关闭后通道结束, 不阻塞在未读取的元素上
+	var drained = make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after Close")
+	}
+	assert.NoError(t, stream.Err())
+	assert.False(t, stream.Next())
+}
+
+func TestStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errno":1,"message":"bad request"}`)
+	}))
+	defer server.Close()
+
+	var client = New("test", 0, time.Second, nil)
+
+	_, err := StreamNDJSON[streamItem](context.Background(), client, http.MethodGet, server.URL, nil)
+	assert.True(t, errors.Is(err, 1))
+	assert.Equal(t, "bad request", err.(errors.Error).Message())
+}